@@ -0,0 +1,196 @@
+package vsic
+
+import (
+	"bytes"
+	"net"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type benchConn struct {
+	r      *bytes.Reader
+	in     []byte
+	writes atomic.Int64
+}
+
+func newBenchConn(in []byte) *benchConn {
+	return &benchConn{r: bytes.NewReader(in), in: in}
+}
+
+func (c *benchConn) Read(b []byte) (int, error) {
+	if c.r.Len() == 0 {
+		c.r.Reset(c.in)
+	}
+	return c.r.Read(b)
+}
+
+func (c *benchConn) Write(b []byte) (int, error) {
+	c.writes.Add(1)
+	return len(b), nil
+}
+
+func (*benchConn) Close() error                     { return nil }
+func (*benchConn) LocalAddr() net.Addr              { return &net.TCPAddr{} }
+func (*benchConn) RemoteAddr() net.Addr             { return &net.TCPAddr{} }
+func (*benchConn) SetDeadline(time.Time) error      { return nil }
+func (*benchConn) SetReadDeadline(time.Time) error  { return nil }
+func (*benchConn) SetWriteDeadline(time.Time) error { return nil }
+
+const benchLine = "MSG alice #general the quick brown fox jumps over the lazy dog"
+
+func BenchmarkReadLine(b *testing.B) {
+	c := Wrap(newBenchConn([]byte(benchLine+"\n")), Config{})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := c.ReadLine(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadLineLong(b *testing.B) {
+	line := "MSG #general " + string(bytes.Repeat([]byte("x"), 3000))
+	c := Wrap(newBenchConn([]byte(line+"\n")), Config{})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := c.ReadLine(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteLine(b *testing.B) {
+	bc := newBenchConn(nil)
+	c := Wrap(bc, Config{})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if err := c.WriteLine(benchLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(bc.writes.Load())/float64(b.N), "writes/op")
+}
+
+// compare with BenchmarkWriteLine: writes/op is the syscall count per line.
+func BenchmarkQueueLine(b *testing.B) {
+	bc := newBenchConn(nil)
+	c := Wrap(bc, Config{CoalesceMs: 1000})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if err := c.QueueLine(benchLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+	c.Flush()
+	b.ReportMetric(float64(bc.writes.Load())/float64(b.N), "writes/op")
+}
+
+// a broadcast tick queueing a burst of lines with the default coalescing
+// settings: writes/op stays well under the burst size.
+func BenchmarkQueueLineBurst(b *testing.B) {
+	bc := newBenchConn(nil)
+	c := Wrap(bc, Config{})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		for range 32 {
+			if err := c.QueueLine(benchLine); err != nil {
+				b.Fatal(err)
+			}
+		}
+		c.Flush()
+	}
+	b.ReportMetric(float64(bc.writes.Load())/float64(b.N), "writes/op")
+}
+
+func BenchmarkParseCommand(b *testing.B) {
+	b.ReportAllocs()
+
+	for b.Loop() {
+		ParseCommand(benchLine)
+	}
+}
+
+func BenchmarkValidator(b *testing.B) {
+	v := NewValidator(4096, nil)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if err := v.Check(benchLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWrap(b *testing.B) {
+	bc := newBenchConn(nil)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		c := Wrap(bc, Config{})
+		c.Release()
+	}
+}
+
+// BenchmarkIdleConn reports the heap each wrapped connection holds while
+// idle, buffers included.
+func BenchmarkIdleConn(b *testing.B) {
+	conns := make([]*Conn, b.N)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	for i := range conns {
+		conns[i] = Wrap(newBenchConn(nil), Config{})
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(b.N), "bytes/conn")
+	runtime.KeepAlive(conns)
+}
+
+// broadcast one line to N clients, building the line per recipient (old
+// way) vs sharing one pre-serialized Frame.
+func BenchmarkBroadcast(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		conns := make([]*Conn, n)
+		for i := range conns {
+			conns[i] = Wrap(newBenchConn(nil), Config{})
+		}
+
+		b.Run("line/"+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				for _, c := range conns {
+					if err := c.WriteLine("MSG alice #general message " + strconv.Itoa(i)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+
+		b.Run("frame/"+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				f, err := NewFrame("MSG alice #general message " + strconv.Itoa(i))
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, c := range conns {
+					if err := c.WriteFrame(f); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/initframs/vsic"
+)
+
+var errNotConnected = errors.New("bot not connected")
+
+type Handler func(m *Message)
+
+type Message struct {
+	Bot    *Bot
+	From   string
+	Target string
+	Text   string
+	Args   []string
+}
+
+func (m *Message) Reply(text string) error {
+	to := m.Target
+	if !strings.HasPrefix(to, "#") {
+		to = m.From
+	}
+	return m.Bot.Send(to, text)
+}
+
+type Bot struct {
+	Addr     string
+	Nick     string
+	Prefix   string
+	Channels []string
+	Config   vsic.Config
+	Store    Store
+
+	SendInterval time.Duration
+	UserCooldown time.Duration
+	MaxBackoff   time.Duration
+	OnDisconnect func(err error)
+
+	commands map[string]Handler
+	mentions []Handler
+
+	mu       sync.Mutex
+	client   *vsic.Client
+	lastUser map[string]time.Time
+
+	sendMu   sync.Mutex
+	lastSend time.Time
+}
+
+func New(addr, nick string) *Bot {
+	return &Bot{
+		Addr:         addr,
+		Nick:         nick,
+		Prefix:       "!",
+		Store:        NewMemoryStore(),
+		SendInterval: 500 * time.Millisecond,
+		UserCooldown: 2 * time.Second,
+		MaxBackoff:   time.Minute,
+		commands:     map[string]Handler{},
+		lastUser:     map[string]time.Time{},
+	}
+}
+
+func (b *Bot) Command(name string, h Handler) {
+	b.commands[strings.ToLower(name)] = h
+}
+
+func (b *Bot) OnMention(h Handler) {
+	b.mentions = append(b.mentions, h)
+}
+
+func (b *Bot) Send(target, text string) error {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	b.mu.Lock()
+	c := b.client
+	b.mu.Unlock()
+
+	if c == nil {
+		return errNotConnected
+	}
+
+	if wait := b.SendInterval - time.Since(b.lastSend); wait > 0 {
+		time.Sleep(wait)
+	}
+	b.lastSend = time.Now()
+
+	return c.Send(target, text)
+}
+
+func (b *Bot) Run(ctx context.Context) error {
+	backoff := time.Second
+
+	for {
+		start := time.Now()
+		err := b.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if b.OnDisconnect != nil {
+			b.OnDisconnect(err)
+		}
+
+		if time.Since(start) > b.MaxBackoff {
+			backoff = time.Second
+		}
+
+		wait := backoff
+		var se *vsic.ServerError
+		if errors.As(err, &se) && se.RetryAfter > wait {
+			wait = se.RetryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > b.MaxBackoff {
+			backoff = b.MaxBackoff
+		}
+	}
+}
+
+func (b *Bot) session(ctx context.Context) error {
+	c, err := vsic.Dial(b.Addr, b.Nick, b.Config)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.client = c
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.client = nil
+		b.mu.Unlock()
+	}()
+
+	stop := context.AfterFunc(ctx, func() { c.Quit("") })
+	defer stop()
+
+	// handlers run on their own goroutine, in order, so one that replies
+	// a few times (each Send waiting out SendInterval) doesn't hold up
+	// Recv and the PONGs it answers.
+	msgs := make(chan *Message, 64)
+	nick := c.Nick
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for m := range msgs {
+			b.dispatch(m, nick)
+		}
+	})
+	defer wg.Wait()
+	defer close(msgs)
+	defer c.Close()
+
+	for _, ch := range b.Channels {
+		if err := c.Join(ch); err != nil {
+			return err
+		}
+	}
+
+	for {
+		line, err := c.Recv()
+		if err != nil {
+			return err
+		}
+
+		cmd, arg := vsic.ParseCommand(line)
+		if cmd != "MSG" {
+			continue
+		}
+
+		from, target, text, ok := vsic.ParseMsg(arg)
+		if !ok || vsic.EqualNick(from, c.Nick) {
+			continue
+		}
+
+		msgs <- &Message{Bot: b, From: from, Target: target, Text: text}
+	}
+}
+
+func (b *Bot) dispatch(m *Message, nick string) {
+	if b.Prefix != "" && strings.HasPrefix(m.Text, b.Prefix) {
+		fields := strings.Fields(strings.TrimPrefix(m.Text, b.Prefix))
+		if len(fields) == 0 {
+			return
+		}
+
+		h, ok := b.commands[strings.ToLower(fields[0])]
+		if !ok || !b.allow(m.From) {
+			return
+		}
+
+		m.Args = fields[1:]
+		h(m)
+		return
+	}
+
+	if len(b.mentions) == 0 || !mentions(m.Text, nick) {
+		return
+	}
+	if !b.allow(m.From) {
+		return
+	}
+
+	m.Args = strings.Fields(m.Text)
+	for _, h := range b.mentions {
+		h(m)
+	}
+}
+
+// mentions reports whether text names nick as a whole word, so a bot
+// called bot answers to "hey bot," but not to "robot".
+func mentions(text, nick string) bool {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_')
+	})
+	for _, w := range words {
+		if vsic.EqualNick(w, nick) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) allow(user string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	user = vsic.FoldNick(user)
+	if last, ok := b.lastUser[user]; ok && time.Since(last) < b.UserCooldown {
+		return false
+	}
+	b.lastUser[user] = time.Now()
+	return true
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/initframs/vsic"
+	"github.com/initframs/vsic/testutil"
+)
+
+func newTestBot(addr string) *Bot {
+	b := New(addr, "helper")
+	b.Channels = []string{"#a"}
+	b.SendInterval = 0
+	b.UserCooldown = 0
+	return b
+}
+
+func TestBotCommandsAndMentions(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, testutil.Script{
+		testutil.Expect("HELLO helper"),
+		testutil.Send("HELLO helper"),
+		testutil.Expect("JOIN #a"),
+		testutil.Send("MSG alice #a !Echo hi there"),
+		testutil.Expect("MSG #a hi there"),
+		testutil.Send("MSG helper #a hey HELPER"),
+		testutil.Send("MSG alice #a helpers wanted"),
+		testutil.Send("MSG alice helper hey Helper,"),
+		testutil.Expect("MSG alice mention"),
+		testutil.Send("MSG alice #a !unknown"),
+		testutil.Send("MSG alice #a !done"),
+		testutil.Expect("BYE"),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := newTestBot(srv.Addr)
+	b.Command("echo", func(m *Message) {
+		m.Reply(strings.Join(m.Args, " "))
+	})
+	b.Command("done", func(m *Message) { cancel() })
+	b.OnMention(func(m *Message) {
+		m.Reply("mention")
+	})
+
+	if err := b.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+// a handler that takes a while mustn't hold up the read loop, or PINGs go
+// unanswered until it's done.
+func TestBotSlowHandler(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, testutil.Script{
+		testutil.Expect("HELLO helper"),
+		testutil.Send("HELLO helper"),
+		testutil.Expect("JOIN #a"),
+		testutil.Send("MSG alice #a !slow"),
+		testutil.Send("PING x"),
+		testutil.Expect("PONG x"),
+		testutil.Expect("MSG #a done"),
+		testutil.Expect("BYE"),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := newTestBot(srv.Addr)
+	b.Command("slow", func(m *Message) {
+		time.Sleep(300 * time.Millisecond)
+		m.Reply("done")
+		cancel()
+	})
+
+	if err := b.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestBotCooldown(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, testutil.Script{
+		testutil.Expect("HELLO helper"),
+		testutil.Send("HELLO helper"),
+		testutil.Expect("JOIN #a"),
+		testutil.Send("MSG alice #a !count"),
+		testutil.Send("MSG ALICE #a !count"),
+		testutil.Send("MSG bob #a !count"),
+		testutil.Expect("MSG #a 1"),
+		testutil.Expect("MSG #a 2"),
+		testutil.Expect("BYE"),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := newTestBot(srv.Addr)
+	b.UserCooldown = time.Minute
+	n := 0
+	b.Command("count", func(m *Message) {
+		n++
+		m.Reply(string(rune('0' + n)))
+		if n == 2 {
+			cancel()
+		}
+	})
+
+	b.Run(ctx)
+	if n != 2 {
+		t.Fatalf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestBotReconnectHonorsRetryAfter(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{},
+		testutil.Script{
+			testutil.Expect("HELLO helper"),
+			testutil.Send("ERROR 503 retry-after=2 restarting"),
+		},
+		testutil.Script{
+			testutil.Expect("HELLO helper"),
+			testutil.Send("HELLO helper"),
+			testutil.Expect("JOIN #a"),
+			testutil.Send("MSG alice #a !ping"),
+			testutil.Expect("BYE"),
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var disconnected time.Time
+	var disconnectErr error
+	b := newTestBot(srv.Addr)
+	b.OnDisconnect = func(err error) {
+		disconnected = time.Now()
+		disconnectErr = err
+	}
+	var gap time.Duration
+	b.Command("ping", func(m *Message) {
+		gap = time.Since(disconnected)
+		cancel()
+	})
+
+	b.Run(ctx)
+
+	var se *vsic.ServerError
+	if !errors.As(disconnectErr, &se) || se.RetryAfter != 2*time.Second {
+		t.Fatalf("OnDisconnect got %v, want the retry-after error", disconnectErr)
+	}
+	if gap < 1900*time.Millisecond {
+		t.Fatalf("reconnected %v after retry-after=2", gap)
+	}
+}
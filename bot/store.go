@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+type Store interface {
+	Get(key string) (string, bool)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+type MemoryStore struct {
+	mu sync.RWMutex
+	m  map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{m: map[string]string{}}
+}
+
+func (s *MemoryStore) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+func (s *MemoryStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+	return nil
+}
+
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: MemoryStore{m: map[string]string{}}, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.m); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+	return s.save()
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+	return s.save()
+}
+
+func (s *FileStore) save() error {
+	data, err := json.Marshal(s.m)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
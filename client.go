@@ -0,0 +1,310 @@
+package vsic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type State int
+
+const (
+	StateConnecting State = iota
+	StateRegistering
+	StateReady
+	StateResuming
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateRegistering:
+		return "registering"
+	case StateReady:
+		return "ready"
+	case StateResuming:
+		return "resuming"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+var errClientClosed = errors.New("client closed")
+
+type Client struct {
+	*Conn
+	pending []string
+
+	// AutoResume makes the Events loop redial and re-register (rejoining
+	// channels) when the connection drops, instead of closing. lines in
+	// the send queue wait for the resumed session.
+	AutoResume bool
+
+	SendRate    float64
+	SendBurst   int
+	SendQueue   int
+	OnSaturated func(queued int)
+
+	cfg  Config
+	nick string
+	dial func() (net.Conn, error)
+
+	mu        sync.Mutex
+	state     State
+	reported  State
+	holdUntil time.Time
+	skew      time.Duration
+	channels  map[string]string
+
+	events     chan Event
+	eventsOnce sync.Once
+
+	outq       chan string
+	outOnce    sync.Once
+	outErr     error
+	outPending atomic.Int64
+}
+
+func Dial(addr, nick string, cfg Config) (*Client, error) {
+	dial := func() (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	return dialClient(dial, nick, cfg)
+}
+
+func DialTLS(addr, nick string, cfg Config, tc *tls.Config) (*Client, error) {
+	dial := func() (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var d net.Dialer
+		nc, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if tc == nil {
+			tc = &tls.Config{}
+		}
+		if tc.ServerName == "" {
+			tc = tc.Clone()
+			tc.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+
+		tlsConn := tls.Client(nc, tc)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return dialClient(dial, nick, cfg)
+}
+
+func dialClient(dial func() (net.Conn, error), nick string, cfg Config) (*Client, error) {
+	nc, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(nc, nick, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.dial = dial
+	return c, nil
+}
+
+func NewClient(nc net.Conn, nick string, cfg Config) (*Client, error) {
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	c := &Client{cfg: cfg, nick: nick, state: StateRegistering, channels: map[string]string{}}
+
+	conn, pending, err := register(nc, nick, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Conn = conn
+	c.pending = pending
+	c.state = StateReady
+	c.scanLimits(pending)
+	return c, nil
+}
+
+func register(nc net.Conn, nick string, cfg Config) (*Conn, []string, error) {
+	cfg.Role = RoleClient
+	c := Wrap(nc, cfg)
+
+	if err := c.WriteLine("HELLO " + nick); err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+
+	var pending []string
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			c.Close()
+			return nil, nil, err
+		}
+
+		cmd, arg := ParseCommand(line)
+		switch cmd {
+		case "HELLO":
+			c.Nick = arg
+			c.Registered()
+			return c, pending, nil
+		case "ERROR":
+			c.Close()
+			return nil, nil, ParseError(arg)
+		case "PING":
+			if err := c.WriteLine(strings.TrimSpace("PONG " + arg)); err != nil {
+				c.Close()
+				return nil, nil, err
+			}
+		default:
+			pending = append(pending, line)
+		}
+	}
+}
+
+func (c *Client) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *Client) setState(s State) {
+	c.mu.Lock()
+	c.state = s
+	c.mu.Unlock()
+}
+
+func (c *Client) conn() *Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn
+}
+
+func (c *Client) Recv() (string, error) {
+	if len(c.pending) > 0 {
+		line := c.pending[0]
+		c.pending = c.pending[1:]
+		return line, nil
+	}
+
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		cmd, arg := ParseCommand(line)
+		switch cmd {
+		case "PING":
+			if err := c.WriteLine(strings.TrimSpace("PONG " + arg)); err != nil {
+				return "", err
+			}
+			continue
+		case "WARN":
+			if strings.HasPrefix(arg, "timeout-in") {
+				if err := c.WriteLine("PING"); err != nil {
+					return "", err
+				}
+			}
+		case "ERROR":
+			if e := ParseError(arg); e.RetryAfter > 0 {
+				c.mu.Lock()
+				c.holdUntil = time.Now().Add(e.RetryAfter)
+				c.mu.Unlock()
+			}
+		case "LIMITS":
+			c.applyLimits(arg)
+		case "TIME":
+			if t, err := ParseTime(arg); err == nil {
+				c.mu.Lock()
+				c.skew = t.Sub(c.cfg.Clock.Now())
+				c.mu.Unlock()
+			}
+		}
+
+		return line, nil
+	}
+}
+
+// send waits out any retry-after the server asked for before writing, so
+// callers don't get rejected again straight away.
+func (c *Client) send(line string) error {
+	c.mu.Lock()
+	wait := time.Until(c.holdUntil)
+	conn := c.Conn
+	limited := c.SendRate > 0 || c.outq != nil
+	c.mu.Unlock()
+
+	if limited {
+		return c.enqueue(line)
+	}
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return conn.WriteLine(line)
+}
+
+func (c *Client) Send(target, text string) error {
+	return c.send("MSG " + target + " " + text)
+}
+
+func (c *Client) Join(channel string) error {
+	c.mu.Lock()
+	c.channels[FoldChannel(channel)] = channel
+	c.mu.Unlock()
+
+	return c.send("JOIN " + channel)
+}
+
+func (c *Client) Part(channel string) error {
+	c.mu.Lock()
+	delete(c.channels, FoldChannel(channel))
+	c.mu.Unlock()
+
+	return c.send("PART " + channel)
+}
+
+func (c *Client) Quit(reason string) error {
+	c.flushQueue(10 * time.Second)
+	c.setState(StateClosed)
+
+	conn := c.conn()
+	_ = conn.WriteLine(strings.TrimSpace("BYE " + reason))
+	return conn.Close()
+}
+
+// ClockSkew is how far the server's clock was ahead of Config.Clock at the
+// last TIME reply. send TIME to refresh it.
+func (c *Client) ClockSkew() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skew
+}
+
+func ParseMsg(arg string) (from, target, text string, ok bool) {
+	parts := strings.SplitN(arg, " ", 3)
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
@@ -0,0 +1,309 @@
+package vsic_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/initframs/vsic"
+	"github.com/initframs/vsic/testutil"
+)
+
+func handshake(nick string) []testutil.Step {
+	return []testutil.Step{
+		testutil.Expect("HELLO " + nick),
+		testutil.Send("HELLO " + nick),
+	}
+}
+
+func TestSendQueueRejectsBadLine(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, append(handshake("bot"),
+		testutil.Expect("MSG #a ok"),
+		testutil.Expect("BYE"),
+	))
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetSendLimit(100, 5)
+
+	if err := c.Send("#a", strings.Repeat("x", 5000)); err == nil {
+		t.Fatal("oversized line was queued")
+	}
+	if err := c.Send("#a", "bad\rline"); err == nil {
+		t.Fatal("line with control chars was queued")
+	}
+	if err := c.Send("#a", "ok"); err != nil {
+		t.Fatalf("send after rejected line: %v", err)
+	}
+
+	if err := c.Quit(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send("#a", "late"); err == nil {
+		t.Fatal("send after Quit returned nil")
+	}
+}
+
+func TestDialEmptyHost(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, append(handshake("bot"), testutil.Expect("BYE")))
+
+	_, port, _ := strings.Cut(srv.Addr, ":")
+	c, err := vsic.Dial(":"+port, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Quit("")
+}
+
+func TestClientRegisterAndRecv(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, testutil.Script{
+		testutil.Expect("HELLO bot"),
+		testutil.Send("NOTICE welcome"),
+		testutil.Send("LIMITS rate=50 burst=2"),
+		testutil.Send("HELLO Bot"),
+		testutil.Send("PING abc"),
+		testutil.Expect("PONG abc"),
+		testutil.Send("MSG alice #a hi"),
+		testutil.Expect("BYE done"),
+	})
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Nick != "Bot" {
+		t.Errorf("nick = %q, want the server's Bot", c.Nick)
+	}
+	if c.SendRate != 50 || c.SendBurst != 2 {
+		t.Errorf("limits = %v/%d, want 50/2 from LIMITS", c.SendRate, c.SendBurst)
+	}
+
+	for _, want := range []string{"NOTICE welcome", "LIMITS rate=50 burst=2", "MSG alice #a hi"} {
+		line, err := c.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Fatalf("Recv = %q, want %q", line, want)
+		}
+	}
+
+	c.Quit("done")
+}
+
+func TestClientRegisterError(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, testutil.Script{
+		testutil.Expect("HELLO bot"),
+		testutil.Send("ERROR 433 nick in use"),
+	})
+
+	_, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	var se *vsic.ServerError
+	if !errors.As(err, &se) || se.Code != vsic.CodeNickInUse {
+		t.Fatalf("err = %v, want a 433 ServerError", err)
+	}
+}
+
+func TestClientSendLimit(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, testutil.Script{
+		testutil.Expect("HELLO bot"),
+		testutil.Send("LIMITS rate=10 burst=1"),
+		testutil.Send("HELLO bot"),
+		testutil.Expect("MSG #a 1"),
+		testutil.Expect("MSG #a 2"),
+		testutil.Expect("MSG #a 3"),
+		testutil.Expect("BYE"),
+	})
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for _, text := range []string{"1", "2", "3"} {
+		if err := c.Send("#a", text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Quit("")
+
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Fatalf("3 lines at 10/s with burst 1 took %v", d)
+	}
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, append(handshake("bot"),
+		testutil.Send("ERROR 429 retry-after=1 slow down"),
+		testutil.Expect("MSG #a again"),
+		testutil.Expect("BYE"),
+	))
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := c.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd, arg := vsic.ParseCommand(line); cmd != "ERROR" || vsic.ParseError(arg).RetryAfter != time.Second {
+		t.Fatalf("Recv = %q, want the retry-after error", line)
+	}
+
+	start := time.Now()
+	if err := c.Send("#a", "again"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 900*time.Millisecond {
+		t.Fatalf("send went out %v after retry-after=1", d)
+	}
+	c.Quit("")
+}
+
+func TestClientResume(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{},
+		append(handshake("bot"),
+			testutil.Expect("JOIN #A"),
+			testutil.Hangup(),
+		),
+		append(handshake("bot"),
+			testutil.Expect("JOIN #A"),
+			testutil.Send("MSG alice #a back"),
+			testutil.Expect("BYE"),
+		),
+	)
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AutoResume = true
+	if err := c.Join("#A"); err != nil {
+		t.Fatal(err)
+	}
+
+	var states []vsic.State
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case ev := <-c.Events():
+			switch ev := ev.(type) {
+			case vsic.StateEvent:
+				states = append(states, ev.To)
+			case vsic.MessageEvent:
+				done = ev.Text == "back"
+			}
+		case <-timeout:
+			t.Fatalf("no message after resume; states %v", states)
+		}
+	}
+
+	want := []vsic.State{vsic.StateResuming, vsic.StateConnecting, vsic.StateRegistering, vsic.StateReady}
+	if !slices.Equal(states, want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+	if srv.Conns() != 2 {
+		t.Errorf("server saw %d connections, want 2", srv.Conns())
+	}
+
+	c.Quit("")
+}
+
+func TestDialLocalhost(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, append(handshake("bot"), testutil.Expect("BYE")))
+
+	_, port, _ := strings.Cut(srv.Addr, ":")
+	c, err := vsic.Dial("localhost:"+port, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Quit("")
+}
+
+func TestClientQuitEvents(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, append(handshake("bot"),
+		testutil.Send("MSG alice bot hi"),
+		testutil.Expect("BYE"),
+	))
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := c.Events()
+	var got []vsic.Event
+	for ev := range events {
+		got = append(got, ev)
+		if _, ok := ev.(vsic.MessageEvent); ok {
+			c.Quit("")
+		}
+	}
+
+	var closed, disconnected int
+	for _, ev := range got {
+		switch ev := ev.(type) {
+		case vsic.StateEvent:
+			if ev.From == vsic.StateReady && ev.To == vsic.StateClosed {
+				closed++
+			}
+		case vsic.DisconnectedEvent:
+			disconnected++
+			if ev.Err != nil {
+				t.Errorf("DisconnectedEvent after Quit has error %v", ev.Err)
+			}
+		}
+	}
+	if closed != 1 || disconnected != 1 {
+		t.Fatalf("events %#v: want one ready->closed StateEvent and one DisconnectedEvent", got)
+	}
+	if c.State() != vsic.StateClosed {
+		t.Fatalf("state = %v after Quit", c.State())
+	}
+}
+
+func TestClientSendDuringResume(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{},
+		append(handshake("bot"), testutil.Hangup()),
+		append(handshake("bot"),
+			testutil.Expect("MSG #a 1"),
+			testutil.Expect("BYE"),
+		),
+	)
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AutoResume = true
+	c.SetSendLimit(100, 5)
+
+	events := c.Events()
+	sent := false
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			se, ok := ev.(vsic.StateEvent)
+			if ok && se.To == vsic.StateResuming && !sent {
+				if err := c.Send("#a", "1"); err != nil {
+					t.Fatal(err)
+				}
+				sent = true
+			}
+			if ok && se.To == vsic.StateReady && sent {
+				c.Quit("")
+				return
+			}
+		case <-timeout:
+			t.Fatal("client never resumed")
+		}
+	}
+}
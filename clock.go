@@ -0,0 +1,30 @@
+package vsic
+
+import "time"
+
+// Clock is where servers and clients should get timestamps from, so tests
+// can swap in a fixed clock. it's only for times that show up in the
+// protocol (WriteTime, Client.ClockSkew); socket deadlines, write
+// coalescing, send pacing and retry-after waits always use the real time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+var SystemClock Clock = systemClock{}
+
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func ParseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// WriteTime answers a TIME request with the connection's clock.
+func (c *Conn) WriteTime() error {
+	return c.WriteLine("TIME " + FormatTime(c.cfg.Clock.Now()))
+}
@@ -0,0 +1,55 @@
+package vsic_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/initframs/vsic"
+	"github.com/initframs/vsic/testutil"
+)
+
+var epoch = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestWriteTime(t *testing.T) {
+	clock := testutil.NewFakeClock(epoch)
+	a, b := net.Pipe()
+	server := vsic.Wrap(a, vsic.Config{Clock: clock})
+	peer := vsic.Wrap(b, vsic.Config{})
+	defer server.Close()
+	defer peer.Close()
+
+	for _, want := range []string{"TIME 2026-01-02T03:04:05Z", "TIME 2026-01-02T03:05:05Z"} {
+		go server.WriteTime()
+		line, err := peer.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Fatalf("got %q, want %q", line, want)
+		}
+		clock.Advance(time.Minute)
+	}
+}
+
+func TestClientClockSkew(t *testing.T) {
+	srv := testutil.StartMockServer(t, vsic.Config{}, append(handshake("bot"),
+		testutil.Expect("TIME"),
+		testutil.Send("TIME "+vsic.FormatTime(epoch.Add(90*time.Second))),
+		testutil.Expect("BYE"),
+	))
+
+	c, err := vsic.Dial(srv.Addr, "bot", vsic.Config{Clock: testutil.NewFakeClock(epoch)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit("")
+
+	c.WriteLine("TIME")
+	if _, err := c.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ClockSkew(); got != 90*time.Second {
+		t.Fatalf("ClockSkew = %v, want 1m30s", got)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/initframs/vsic"
+)
+
+type result struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	sent      atomic.Int64
+	received  atomic.Int64
+	failed    atomic.Int64
+}
+
+func (r *result) record(d time.Duration) {
+	r.mu.Lock()
+	r.latencies = append(r.latencies, d)
+	r.mu.Unlock()
+	r.received.Add(1)
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6667", "server address")
+	clients := flag.Int("clients", 50, "number of concurrent clients")
+	rate := flag.Float64("rate", 1, "messages per second per client")
+	duration := flag.Duration("duration", 30*time.Second, "how long to send for")
+	channel := flag.String("channel", "#bench", "channel to send to")
+	useTLS := flag.Bool("tls", false, "connect with tls")
+	insecure := flag.Bool("insecure", false, "skip tls certificate verification")
+	flag.Parse()
+
+	if *clients < 1 || *rate <= 0 {
+		fmt.Fprintln(os.Stderr, "vsic-bench: -clients and -rate must be positive")
+		os.Exit(2)
+	}
+
+	run := strconv.FormatInt(time.Now().Unix()%100000, 10)
+	res := &result{}
+	conns := make([]*vsic.Client, 0, *clients)
+
+	for i := 0; i < *clients; i++ {
+		nick := "b" + run + "_" + strconv.Itoa(i)
+
+		var c *vsic.Client
+		var err error
+		if *useTLS {
+			c, err = vsic.DialTLS(*addr, nick, vsic.Config{}, &tls.Config{InsecureSkipVerify: *insecure})
+		} else {
+			c, err = vsic.Dial(*addr, nick, vsic.Config{})
+		}
+		if err != nil {
+			res.failed.Add(1)
+			continue
+		}
+
+		if err := c.Join(*channel); err != nil {
+			res.failed.Add(1)
+			c.Close()
+			continue
+		}
+		conns = append(conns, c)
+	}
+
+	if len(conns) == 0 {
+		fmt.Fprintln(os.Stderr, "vsic-bench: no clients could connect")
+		os.Exit(1)
+	}
+
+	var readers sync.WaitGroup
+	for _, c := range conns {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			read(c, res)
+		}()
+	}
+
+	start := time.Now()
+	stop := time.Now().Add(*duration)
+	interval := time.Duration(float64(time.Second) / *rate)
+
+	var senders sync.WaitGroup
+	for _, c := range conns {
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+
+			t := time.NewTicker(interval)
+			defer t.Stop()
+
+			for now := range t.C {
+				if now.After(stop) {
+					return
+				}
+				if err := c.Send(*channel, "bench "+strconv.FormatInt(time.Now().UnixNano(), 10)); err != nil {
+					return
+				}
+				res.sent.Add(1)
+			}
+		}()
+	}
+
+	senders.Wait()
+	time.Sleep(2 * time.Second)
+	elapsed := time.Since(start)
+
+	for _, c := range conns {
+		c.Quit("bench done")
+	}
+	readers.Wait()
+
+	report(res, len(conns), elapsed)
+}
+
+func read(c *vsic.Client, res *result) {
+	for {
+		line, err := c.Recv()
+		if err != nil {
+			return
+		}
+
+		cmd, arg := vsic.ParseCommand(line)
+		if cmd != "MSG" {
+			continue
+		}
+
+		from, _, text, ok := vsic.ParseMsg(arg)
+		if !ok || vsic.EqualNick(from, c.Nick) || !strings.HasPrefix(text, "bench ") {
+			continue
+		}
+
+		ns, err := strconv.ParseInt(strings.TrimPrefix(text, "bench "), 10, 64)
+		if err != nil {
+			continue
+		}
+		res.record(time.Since(time.Unix(0, ns)))
+	}
+}
+
+func report(res *result, clients int, elapsed time.Duration) {
+	sent := res.sent.Load()
+	received := res.received.Load()
+	expected := sent * int64(clients-1)
+
+	fmt.Printf("clients:   %d connected, %d failed\n", clients, res.failed.Load())
+	fmt.Printf("sent:      %d (%.1f msg/s)\n", sent, float64(sent)/elapsed.Seconds())
+	fmt.Printf("delivered: %d of %d expected\n", received, expected)
+	if expected > 0 {
+		fmt.Printf("dropped:   %d (%.2f%%)\n", expected-received, 100*float64(expected-received)/float64(expected))
+	}
+
+	res.mu.Lock()
+	lat := res.latencies
+	res.mu.Unlock()
+
+	if len(lat) == 0 {
+		return
+	}
+
+	slices.Sort(lat)
+	pct := func(p float64) time.Duration {
+		return lat[min(len(lat)-1, int(p*float64(len(lat))))]
+	}
+
+	fmt.Printf("latency:   p50 %v  p90 %v  p99 %v  max %v\n", pct(0.50), pct(0.90), pct(0.99), lat[len(lat)-1])
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/initframs/vsic"
+)
+
+type counters struct {
+	actions    [len(actions)]atomic.Int64
+	probeFails atomic.Int64
+	canaryLost atomic.Int64
+	canarySent atomic.Int64
+}
+
+var actions = [...]struct {
+	name string
+	run  func(addr string, r *rand.Rand)
+}{
+	{"normal", normal},
+	{"slow", slow},
+	{"partial", partial},
+	{"reset", reset},
+	{"malformed", malformed},
+	{"oversized", oversized},
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6667", "server address")
+	workers := flag.Int("workers", 20, "concurrent chaos workers")
+	duration := flag.Duration("duration", 10*time.Minute, "how long to run")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "random seed")
+	flag.Parse()
+
+	fmt.Printf("vsic-chaos: %d workers against %s for %v (seed %d)\n", *workers, *addr, *duration, *seed)
+
+	var n counters
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(*seed, uint64(i)))
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				a := r.IntN(len(actions))
+				actions[a].run(*addr, r)
+				n.actions[a].Add(1)
+				time.Sleep(time.Duration(r.IntN(50)) * time.Millisecond)
+			}
+		}()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		probe(*addr, stop, &n)
+	}()
+	go func() {
+		defer wg.Done()
+		canary(*addr, stop, &n)
+	}()
+
+	time.Sleep(*duration)
+	close(stop)
+	wg.Wait()
+
+	for i, a := range actions {
+		fmt.Printf("%-10s %d\n", a.name, n.actions[i].Load())
+	}
+	fmt.Printf("probe failures: %d\n", n.probeFails.Load())
+	fmt.Printf("canary: %d sent, %d lost\n", n.canarySent.Load(), n.canaryLost.Load())
+
+	if n.probeFails.Load() > 0 || n.canaryLost.Load() > 0 {
+		fmt.Println("vsic-chaos: FAIL")
+		os.Exit(1)
+	}
+	fmt.Println("vsic-chaos: ok")
+}
+
+func nick(r *rand.Rand) string {
+	return "chaos_" + strconv.Itoa(r.IntN(1000000))
+}
+
+func normal(addr string, r *rand.Rand) {
+	c, err := vsic.Dial(addr, nick(r), vsic.Config{})
+	if err != nil {
+		return
+	}
+	defer c.Close()
+
+	_ = c.Join("#chaos")
+	for i := 0; i < 1+r.IntN(5); i++ {
+		_ = c.Send("#chaos", "hello "+strconv.Itoa(i))
+	}
+	_ = c.Quit("")
+}
+
+func slow(addr string, r *rand.Rand) {
+	nc, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return
+	}
+	defer nc.Close()
+
+	for _, b := range []byte("HELLO " + nick(r) + "\n") {
+		if _, err := nc.Write([]byte{b}); err != nil {
+			return
+		}
+		time.Sleep(time.Duration(r.IntN(200)) * time.Millisecond)
+	}
+}
+
+func partial(addr string, r *rand.Rand) {
+	nc, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return
+	}
+	line := "HELLO " + nick(r)
+	_, _ = nc.Write([]byte(line[:r.IntN(len(line))]))
+	nc.Close()
+}
+
+func reset(addr string, r *rand.Rand) {
+	c, err := vsic.Dial(addr, nick(r), vsic.Config{})
+	if err != nil {
+		return
+	}
+	_ = c.Join("#chaos")
+	if tc, ok := c.NetConn.(*net.TCPConn); ok {
+		_ = tc.SetLinger(0)
+	}
+	c.Close()
+}
+
+func malformed(addr string, r *rand.Rand) {
+	nc, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return
+	}
+	defer nc.Close()
+
+	garbage := []string{
+		"\r\n",
+		"HELLO\r\r\n",
+		"\x00\x01\x02\n",
+		"MSG\n",
+		"hello lowercase\n",
+		"JOIN \xff\xfe\n",
+		"   \n",
+		"PING " + strings.Repeat("\t", 50) + "\n",
+	}
+	for i := 0; i < 1+r.IntN(10); i++ {
+		if _, err := nc.Write([]byte(garbage[r.IntN(len(garbage))])); err != nil {
+			return
+		}
+	}
+}
+
+func oversized(addr string, r *rand.Rand) {
+	nc, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return
+	}
+	defer nc.Close()
+
+	_, _ = nc.Write([]byte("MSG #chaos " + strings.Repeat("x", 4096+r.IntN(65536)) + "\n"))
+}
+
+// probe checks the server keeps accepting and registering new clients.
+func probe(addr string, stop <-chan struct{}, n *counters) {
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		c, err := vsic.Dial(addr, "probe_"+strconv.Itoa(rand.IntN(100000)), vsic.Config{TimeoutSec: 5})
+		if err != nil {
+			n.probeFails.Add(1)
+			fmt.Println("probe failed:", err)
+			continue
+		}
+		_ = c.Quit("")
+	}
+}
+
+// canary keeps two well-behaved clients talking and counts messages that
+// don't arrive, i.e. chaos elsewhere leaking into healthy sessions.
+func canary(addr string, stop <-chan struct{}, n *counters) {
+	tx, err := vsic.Dial(addr, "canary_tx", vsic.Config{})
+	if err != nil {
+		n.probeFails.Add(1)
+		fmt.Println("canary connect failed:", err)
+		return
+	}
+	defer tx.Close()
+
+	rx, err := vsic.Dial(addr, "canary_rx", vsic.Config{})
+	if err != nil {
+		n.probeFails.Add(1)
+		fmt.Println("canary connect failed:", err)
+		return
+	}
+	defer rx.Close()
+
+	_ = tx.Join("#chaos-canary")
+	_ = rx.Join("#chaos-canary")
+
+	// tx only sends, but it still has to answer PINGs and timeout warnings
+	// or the server will drop it partway through a long soak
+	go func() {
+		for {
+			if _, err := tx.Recv(); err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					continue
+				}
+				return
+			}
+		}
+	}()
+
+	got := make(chan string, 64)
+	go func() {
+		for {
+			line, err := rx.Recv()
+			if err != nil {
+				close(got)
+				return
+			}
+			cmd, arg := vsic.ParseCommand(line)
+			if cmd != "MSG" {
+				continue
+			}
+			if _, _, text, ok := vsic.ParseMsg(arg); ok {
+				got <- text
+			}
+		}
+	}()
+
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	for i := 0; ; i++ {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		want := "canary " + strconv.Itoa(i)
+		if err := tx.Send("#chaos-canary", want); err != nil {
+			n.canaryLost.Add(1)
+			fmt.Println("canary send failed:", err)
+			return
+		}
+		n.canarySent.Add(1)
+
+		if !waitFor(got, want, 5*time.Second) {
+			n.canaryLost.Add(1)
+			fmt.Println("canary message lost:", want)
+		}
+	}
+}
+
+func waitFor(got <-chan string, want string, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case text, ok := <-got:
+			if !ok {
+				return false
+			}
+			if text == want {
+				return true
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
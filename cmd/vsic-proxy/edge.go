@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// banList is a set of ips/cidrs, used for both the ban and allow lists.
+type banList struct {
+	nets []*net.IPNet
+}
+
+func loadBans(path string) (*banList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &banList{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.Contains(line, "/") {
+			if ip := net.ParseIP(line); ip != nil && ip.To4() != nil {
+				line += "/32"
+			} else {
+				line += "/128"
+			}
+		}
+
+		_, ipnet, err := net.ParseCIDR(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		b.nets = append(b.nets, ipnet)
+	}
+
+	return b, sc.Err()
+}
+
+func (b *banList) match(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range b.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+func (b *bucket) take() bool {
+	if b.rate <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func proxyHeader(src, dst net.Addr) string {
+	s, sok := src.(*net.TCPAddr)
+	d, dok := dst.(*net.TCPAddr)
+	if !sok || !dok {
+		return "PROXY UNKNOWN\r\n"
+	}
+
+	family := "TCP4"
+	if s.IP.To4() == nil {
+		family = "TCP6"
+	}
+
+	return fmt.Sprintf("PROXY %s %s %s %d %d\r\n", family, s.IP, d.IP, s.Port, d.Port)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/initframs/vsic"
+)
+
+type proxy struct {
+	backends  []string
+	next      atomic.Uint64
+	bans      *banList
+	allow     *banList
+	sendPROXY bool
+	rate      float64
+	burst     float64
+	maxPerIP  int
+	cfg       vsic.Config
+
+	mu    sync.Mutex
+	perIP map[string]int
+}
+
+func main() {
+	listen := flag.String("listen", ":6697", "address to accept clients on")
+	backends := flag.String("backend", "127.0.0.1:6667", "comma-separated vsicd backends")
+	certFile := flag.String("tls-cert", "", "certificate for tls termination")
+	keyFile := flag.String("tls-key", "", "key for tls termination")
+	bansFile := flag.String("bans", "", "file of banned ips/cidrs, one per line")
+	allowFile := flag.String("allow", "", "allow-only mode: file of ips/cidrs that may connect, one per line")
+	rate := flag.Float64("rate", 5, "lines per second allowed per client")
+	burst := flag.Float64("burst", 10, "burst size for the rate limit")
+	maxPerIP := flag.Int("max-per-ip", 5, "max concurrent connections per ip (0 for no limit)")
+	proxyProto := flag.Bool("proxy-protocol", true, "send a PROXY v1 header to backends")
+	maxMsg := flag.Int("max-msg", 4096, "longest line forwarded either way; match vsicd's max message size")
+	timeout := flag.Int("timeout", 300, "seconds a client or backend may stay silent; keep above vsicd's ping interval")
+	flag.Parse()
+
+	p := &proxy{
+		bans:      &banList{},
+		sendPROXY: *proxyProto,
+		rate:      *rate,
+		burst:     *burst,
+		maxPerIP:  *maxPerIP,
+		cfg:       vsic.Config{MaxMsgSize: *maxMsg, TimeoutSec: *timeout},
+		perIP:     map[string]int{},
+	}
+	for _, b := range strings.Split(*backends, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			p.backends = append(p.backends, b)
+		}
+	}
+	if len(p.backends) == 0 {
+		log.Fatal("vsic-proxy: no backends configured")
+	}
+
+	if *bansFile != "" {
+		bans, err := loadBans(*bansFile)
+		if err != nil {
+			log.Fatal("vsic-proxy: ", err)
+		}
+		p.bans = bans
+	}
+
+	if *allowFile != "" {
+		allow, err := loadBans(*allowFile)
+		if err != nil {
+			log.Fatal("vsic-proxy: ", err)
+		}
+		if len(allow.nets) == 0 {
+			log.Fatal("vsic-proxy: allow list ", *allowFile, " is empty, nobody could connect")
+		}
+		p.allow = allow
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal("vsic-proxy: ", err)
+	}
+
+	if *certFile != "" || *keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			log.Fatal("vsic-proxy: ", err)
+		}
+		l = tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	}
+
+	log.Printf("vsic-proxy: listening on %s, forwarding to %s", l.Addr(), strings.Join(p.backends, ", "))
+
+	// errors like EMFILE under load are temporary, so back off and keep
+	// accepting; only a closed listener ends the loop
+	backoff := 5 * time.Millisecond
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				log.Fatal("vsic-proxy: ", err)
+			}
+			log.Printf("vsic-proxy: accept: %v, retrying in %v", err, backoff)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, time.Second)
+			continue
+		}
+		backoff = 5 * time.Millisecond
+		go p.handle(c)
+	}
+}
+
+func (p *proxy) handle(nc net.Conn) {
+	client := vsic.Wrap(nc, p.cfg)
+	defer client.Close()
+
+	ip := hostOf(nc.RemoteAddr())
+
+	if p.allow != nil && !p.allow.match(ip) {
+		log.Printf("vsic-proxy: %s: closed: not in allow list", ip)
+		_ = client.CloseWithError(vsic.CodeBanned, "not allowed")
+		return
+	}
+
+	if p.bans.match(ip) {
+		log.Printf("vsic-proxy: %s: closed: banned", ip)
+		_ = client.CloseWithError(vsic.CodeBanned, "")
+		return
+	}
+
+	if !p.acquire(ip) {
+		log.Printf("vsic-proxy: %s: closed: too many connections", ip)
+		_ = client.WriteLine(vsic.FormatRetryError(vsic.CodeRateLimit, 10*time.Second, "too many connections"))
+		return
+	}
+	defer p.release(ip)
+
+	bc, err := p.dial(nc)
+	if err != nil {
+		log.Printf("vsic-proxy: %s: no backend available: %v", ip, err)
+		_ = client.WriteLine(vsic.FormatRetryError(vsic.CodeShutdown, 5*time.Second, "server unavailable"))
+		return
+	}
+	backend := vsic.Wrap(bc, p.cfg)
+	defer backend.Close()
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		defer func() { done <- struct{}{} }()
+		for {
+			line, err := backend.ReadLine()
+			if err != nil {
+				return
+			}
+			if err := client.WriteLine(line); err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer func() { done <- struct{}{} }()
+		bucket := newBucket(p.rate, p.burst)
+		for {
+			line, err := client.ReadLine()
+			if err != nil {
+				return
+			}
+			if !bucket.take() {
+				log.Printf("vsic-proxy: %s: closed: rate limited", ip)
+				// long enough for a full burst to refill
+				wait := time.Duration(p.burst / p.rate * float64(time.Second))
+				_ = client.WriteLine(vsic.FormatRetryError(vsic.CodeRateLimit, wait, ""))
+				return
+			}
+			if err := backend.WriteLine(line); err != nil {
+				return
+			}
+		}
+	}()
+
+	<-done
+}
+
+func (p *proxy) dial(client net.Conn) (net.Conn, error) {
+	start := p.next.Add(1)
+	var err error
+
+	for i := range p.backends {
+		addr := p.backends[(int(start)+i)%len(p.backends)]
+
+		var bc net.Conn
+		bc, err = net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			continue
+		}
+
+		if p.sendPROXY {
+			if _, err = bc.Write([]byte(proxyHeader(client.RemoteAddr(), client.LocalAddr()))); err != nil {
+				bc.Close()
+				continue
+			}
+		}
+		return bc, nil
+	}
+
+	return nil, err
+}
+
+func (p *proxy) acquire(ip string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.maxPerIP > 0 && p.perIP[ip] >= p.maxPerIP {
+		return false
+	}
+	p.perIP[ip]++
+	return true
+}
+
+func (p *proxy) release(ip string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.perIP[ip]--; p.perIP[ip] <= 0 {
+		delete(p.perIP, ip)
+	}
+}
+
+func hostOf(a net.Addr) string {
+	host, _, err := net.SplitHostPort(a.String())
+	if err != nil {
+		return a.String()
+	}
+	return host
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/initframs/vsic"
+)
+
+const scrollback = 200
+
+type session struct {
+	c        *vsic.Client
+	quitting atomic.Bool
+
+	mu      sync.Mutex
+	current string
+	tabs    []string
+	buffers map[string][]string
+}
+
+func connect(args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	df := addDialFlags(fs)
+
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("usage: vsic connect host:port --nick NICK")
+	}
+
+	c, err := df.dial(pos[0])
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	s := &session{c: c, current: "*", tabs: []string{"*"}, buffers: map[string][]string{}}
+	s.show("*", "connected to "+pos[0]+" as "+c.Nick+" (/help for commands)")
+
+	done := make(chan error, 1)
+	go func() { done <- s.readLoop() }()
+
+	input := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			input <- sc.Text()
+		}
+		close(input)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case line, ok := <-input:
+			quit, err := s.handleInput(line)
+			if err != nil {
+				return err
+			}
+			if quit || !ok {
+				s.quitting.Store(true)
+				return c.Quit("")
+			}
+		}
+	}
+}
+
+func (s *session) readLoop() error {
+	for {
+		line, err := s.c.Recv()
+		if err != nil {
+			if s.quitting.Load() {
+				return nil
+			}
+			s.show("*", "disconnected: "+err.Error())
+			return err
+		}
+
+		cmd, arg := vsic.ParseCommand(line)
+		switch cmd {
+		case "MSG":
+			from, target, text, ok := vsic.ParseMsg(arg)
+			if !ok {
+				continue
+			}
+			tab := target
+			if !strings.HasPrefix(tab, "#") {
+				tab = from
+			}
+			s.show(tab, "<"+from+"> "+text)
+		case "JOIN", "PART":
+			s.show("*", strings.ToLower(cmd)+" "+arg)
+		default:
+			s.show("*", line)
+		}
+	}
+}
+
+func (s *session) handleInput(line string) (bool, error) {
+	if line == "" {
+		return false, nil
+	}
+
+	if !strings.HasPrefix(line, "/") {
+		s.mu.Lock()
+		target := s.current
+		s.mu.Unlock()
+
+		if target == "*" {
+			s.show("*", "no channel selected, /join one first")
+			return false, nil
+		}
+		s.show(target, "<"+s.c.Nick+"> "+line)
+		return false, s.c.Send(target, line)
+	}
+
+	cmd, arg := vsic.ParseCommand(strings.TrimPrefix(line, "/"))
+	switch strings.ToLower(cmd) {
+	case "quit", "q":
+		return true, nil
+	case "join", "j":
+		s.addTab(arg)
+		return false, s.c.Join(arg)
+	case "part":
+		if arg == "" {
+			arg = s.currentTab()
+		}
+		if arg == "*" {
+			s.show("*", "not in a channel")
+			return false, nil
+		}
+		s.removeTab(arg)
+		if !strings.HasPrefix(arg, "#") {
+			// a query tab, nothing to leave on the server
+			return false, nil
+		}
+		return false, s.c.Part(arg)
+	case "msg":
+		target, text, _ := strings.Cut(arg, " ")
+		s.addTab(target)
+		s.show(target, "<"+s.c.Nick+"> "+text)
+		return false, s.c.Send(target, text)
+	case "switch", "w":
+		s.switchTab(arg)
+	case "tabs":
+		s.mu.Lock()
+		s.print("tabs: " + strings.Join(s.tabs, " "))
+		s.mu.Unlock()
+	case "raw":
+		return false, s.c.WriteLine(arg)
+	case "help":
+		s.show("*", "/join #chan, /part [#chan], /msg nick text, /switch tab, /tabs, /raw LINE, /quit")
+	default:
+		s.show("*", "unknown command /"+cmd)
+	}
+
+	return false, nil
+}
+
+func (s *session) currentTab() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// tab returns the open tab matching name, or "" if there isn't one.
+// callers hold mu.
+func (s *session) tab(name string) string {
+	for _, t := range s.tabs {
+		if vsic.EqualChannel(t, name) {
+			return t
+		}
+	}
+	return ""
+}
+
+func (s *session) addTab(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t := s.tab(name); t != "" {
+		s.current = t
+		return
+	}
+	s.tabs = append(s.tabs, name)
+	s.current = name
+}
+
+func (s *session) removeTab(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.tabs {
+		if vsic.EqualChannel(t, name) && t != "*" {
+			s.tabs = append(s.tabs[:i], s.tabs[i+1:]...)
+			delete(s.buffers, vsic.FoldChannel(t))
+			break
+		}
+	}
+	if vsic.EqualChannel(s.current, name) {
+		s.current = "*"
+	}
+}
+
+func (s *session) switchTab(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tab(name)
+	if t == "" {
+		s.print("no such tab " + name)
+		return
+	}
+
+	s.current = t
+	s.print("--- " + t + " ---")
+	for _, l := range s.buffers[vsic.FoldChannel(t)] {
+		fmt.Println(l)
+	}
+}
+
+func (s *session) show(tab, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := time.Now().Format("15:04") + " " + text
+
+	// a query someone else opened gets its own tab
+	if s.tab(tab) == "" {
+		s.tabs = append(s.tabs, tab)
+	}
+
+	key := vsic.FoldChannel(tab)
+	buf := append(s.buffers[key], line)
+	if len(buf) > scrollback {
+		buf = buf[len(buf)-scrollback:]
+	}
+	s.buffers[key] = buf
+
+	if vsic.EqualChannel(tab, s.current) || tab == "*" {
+		fmt.Println(line)
+	} else {
+		fmt.Println("[" + tab + "] " + line)
+	}
+}
+
+func (s *session) print(text string) {
+	fmt.Println(text)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/initframs/vsic"
+)
+
+const usage = `usage: vsic <command> [args]
+
+commands:
+  connect host:port --nick NICK [--tls] [--insecure]   interactive client
+  send host:port --nick NICK --channel #chan ["text"]  post text (or stdin lines) and exit
+  record host:port [--listen addr] [-o file]           proxy one session and record its traffic
+  replay file host:port [--speed N] [--check]          play a recorded session back against a server
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "connect":
+		err = connect(os.Args[2:])
+	case "send":
+		err = send(os.Args[2:])
+	case "record":
+		err = record(os.Args[2:])
+	case "replay":
+		err = replay(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "vsic: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vsic:", err)
+		os.Exit(1)
+	}
+}
+
+type dialFlags struct {
+	nick     *string
+	useTLS   *bool
+	insecure *bool
+}
+
+func addDialFlags(fs *flag.FlagSet) dialFlags {
+	return dialFlags{
+		nick:     fs.String("nick", "", "nick to register with"),
+		useTLS:   fs.Bool("tls", false, "connect with tls"),
+		insecure: fs.Bool("insecure", false, "skip tls certificate verification"),
+	}
+}
+
+func (d dialFlags) dial(addr string) (*vsic.Client, error) {
+	if !vsic.ValidNick(*d.nick) {
+		return nil, fmt.Errorf("invalid nick %q", *d.nick)
+	}
+
+	if *d.useTLS {
+		return vsic.DialTLS(addr, *d.nick, vsic.Config{}, &tls.Config{InsecureSkipVerify: *d.insecure})
+	}
+	return vsic.Dial(addr, *d.nick, vsic.Config{})
+}
+
+// parse lets flags come after positional args, so `vsic connect host:port
+// --nick me` works the same as putting --nick first.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordings are plain text, one line per protocol line:
+//
+//	<ms since start> <direction> <line>
+//
+// where direction is > for client to server and < for server to client.
+// lines are the raw bytes up to each \n, unvalidated, so stray \r or
+// control bytes and oversized lines are kept exactly as they were sent.
+
+func record(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:6668", "local address clients connect to")
+	out := fs.String("o", "session.rec", "file to write the recording to")
+
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("usage: vsic record host:port [--listen addr] [-o file]")
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "vsic: recording, point your client at", l.Addr())
+
+	cc, err := l.Accept()
+	l.Close()
+	if err != nil {
+		return err
+	}
+
+	sc, err := net.DialTimeout("tcp", pos[0], 10*time.Second)
+	if err != nil {
+		cc.Close()
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		cc.Close()
+		sc.Close()
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	var mu sync.Mutex
+	start := time.Now()
+	write := func(dir, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "%d %s %s\n", time.Since(start).Milliseconds(), dir, line)
+	}
+
+	var wg sync.WaitGroup
+	pipe := func(from, to net.Conn, dir string) {
+		defer wg.Done()
+		defer from.Close()
+		defer to.Close()
+
+		// forward every read as-is and only split on \n for the recording,
+		// so the peer sees exactly the bytes and timing it would have
+		var line []byte
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := from.Read(buf)
+			if n > 0 {
+				if _, err := to.Write(buf[:n]); err != nil {
+					return
+				}
+
+				rest := buf[:n]
+				for {
+					i := bytes.IndexByte(rest, '\n')
+					if i == -1 {
+						line = append(line, rest...)
+						break
+					}
+					write(dir, string(append(line, rest[:i]...)))
+					line = line[:0]
+					rest = rest[i+1:]
+				}
+			}
+			if err != nil {
+				if len(line) > 0 {
+					write(dir, string(line))
+				}
+				return
+			}
+		}
+	}
+
+	wg.Add(2)
+	go pipe(cc, sc, ">")
+	go pipe(sc, cc, "<")
+	wg.Wait()
+
+	fmt.Fprintln(os.Stderr, "vsic: session recorded to", *out)
+	return nil
+}
+
+type recLine struct {
+	at   time.Duration
+	dir  string
+	line string
+}
+
+func loadRecording(path string) ([]recLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []recLine
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		text, err := readRaw(r)
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.SplitN(text, " ", 3)
+		if len(parts) != 3 || (parts[1] != ">" && parts[1] != "<") {
+			return nil, fmt.Errorf("%s:%d: malformed recording line", path, n)
+		}
+
+		ms, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad timestamp", path, n)
+		}
+		recs = append(recs, recLine{at: time.Duration(ms) * time.Millisecond, dir: parts[1], line: parts[2]})
+	}
+}
+
+// readRaw reads up to the next \n and returns the line without it; a
+// \r before it is part of the line.
+func readRaw(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		return line, nil
+	}
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speed := fs.Float64("speed", 1, "playback speed multiplier (0 sends as fast as possible)")
+	check := fs.Bool("check", false, "compare server replies against the recording")
+
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return errors.New("usage: vsic replay file host:port [--speed N] [--check]")
+	}
+
+	recs, err := loadRecording(pos[0])
+	if err != nil {
+		return err
+	}
+
+	nc, err := net.DialTimeout("tcp", pos[1], 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := bufio.NewReader(nc)
+		for {
+			line, err := readRaw(r)
+			if err != nil {
+				return
+			}
+			fmt.Println("<", line)
+			got = append(got, line)
+		}
+	}()
+
+	start := time.Now()
+	var last time.Duration
+	for _, r := range recs {
+		last = r.at
+		if r.dir != ">" {
+			continue
+		}
+
+		if *speed > 0 {
+			if wait := time.Duration(float64(r.at)/(*speed)) - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		fmt.Println(">", r.line)
+		if _, err := io.WriteString(nc, r.line+"\n"); err != nil {
+			return err
+		}
+	}
+
+	tail := time.Second
+	if *speed > 0 {
+		tail = max(tail, time.Duration(float64(last)/(*speed))-time.Since(start))
+	}
+	time.Sleep(tail)
+	nc.Close()
+	<-done
+
+	if !*check {
+		return nil
+	}
+
+	var want []string
+	for _, r := range recs {
+		if r.dir == "<" {
+			want = append(want, r.line)
+		}
+	}
+
+	mismatches := 0
+	for i := 0; i < max(len(want), len(got)); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			mismatches++
+			fmt.Fprintf(os.Stderr, "line %d: recorded %q, got %q\n", i+1, w, g)
+		}
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d server lines differ from the recording", mismatches, len(want))
+	}
+	fmt.Fprintln(os.Stderr, "vsic: replay matches recording")
+	return nil
+}
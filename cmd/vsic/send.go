@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+)
+
+func send(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	df := addDialFlags(fs)
+	channel := fs.String("channel", "", "channel to post to")
+	to := fs.String("to", "", "nick to message directly instead of a channel")
+
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) < 1 || (*channel == "") == (*to == "") {
+		return errors.New(`usage: vsic send host:port --nick NICK (--channel #chan | --to nick) ["text"]`)
+	}
+
+	text := strings.Join(pos[1:], " ")
+	if len(pos) > 1 && text == "" {
+		return errors.New("no text to send")
+	}
+
+	// dial before reading stdin, so `tail -f log | vsic send` posts each
+	// line as it comes instead of waiting for an EOF that never does.
+	c, err := df.dial(pos[0])
+	if err != nil {
+		return err
+	}
+
+	target := *to
+	if *channel != "" {
+		target = *channel
+		if err := c.Join(target); err != nil {
+			c.Close()
+			return err
+		}
+	}
+
+	if len(pos) > 1 {
+		if err := c.Send(target, text); err != nil {
+			c.Close()
+			return err
+		}
+		return c.Quit("")
+	}
+
+	sent := 0
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		if sc.Text() == "" {
+			continue
+		}
+		if err := c.Send(target, sc.Text()); err != nil {
+			c.Close()
+			return err
+		}
+		sent++
+	}
+	if err := sc.Err(); err != nil {
+		c.Close()
+		return err
+	}
+
+	if err := c.Quit(""); err != nil {
+		return err
+	}
+	if sent == 0 {
+		return errors.New("no text on stdin")
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package vsic
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+type Command struct {
+	Name    string
+	Args    int
+	Cap     string
+	Syntax  string
+	Summary string
+
+	ServerOnly bool
+	Oper       bool
+}
+
+var Commands = map[string]Command{
+	"HELLO":   {Name: "HELLO", Args: 1, Syntax: "<nick>", Summary: "register with a nick"},
+	"MSG":     {Name: "MSG", Args: 2, Syntax: "<target> <text>", Summary: "send text to a channel or nick"},
+	"JOIN":    {Name: "JOIN", Args: 1, Syntax: "<channel>", Summary: "join a channel"},
+	"PART":    {Name: "PART", Args: 1, Syntax: "<channel>", Summary: "leave a channel"},
+	"WHO":     {Name: "WHO", Syntax: "[channel]", Summary: "list users"},
+	"PING":    {Name: "PING", Syntax: "[token]", Summary: "check the connection is alive"},
+	"PONG":    {Name: "PONG", Syntax: "[token]", Summary: "answer a PING"},
+	"BYE":     {Name: "BYE", Syntax: "[reason]", Summary: "disconnect"},
+	"HELP":    {Name: "HELP", Syntax: "[command]", Summary: "list commands or show one command's syntax"},
+	"TIME":    {Name: "TIME", Syntax: "[timestamp]", Summary: "get the server's current time (RFC 3339)"},
+	"NOTICE":  {Name: "NOTICE", Args: 1, ServerOnly: true},
+	"ERROR":   {Name: "ERROR", Args: 1, ServerOnly: true},
+	"WARN":    {Name: "WARN", Args: 1, ServerOnly: true},
+	"LIMITS":  {Name: "LIMITS", Args: 1, ServerOnly: true},
+	"PAYLOAD": {Name: "PAYLOAD", Args: 1, Cap: "payload", Syntax: "<size> | ACK <bytes>", Summary: "start or acknowledge a streamed payload"},
+	"CHUNK":   {Name: "CHUNK", Args: 1, Cap: "payload", Syntax: "<base64>", Summary: "one chunk of a streamed payload"},
+}
+
+var reserved = func() map[string]bool {
+	m := map[string]bool{}
+	for name := range Commands {
+		m[name] = true
+	}
+	return m
+}()
+
+// Register adds a server-specific command to the table. the built-in
+// protocol keywords are reserved and can't be redefined. Commands isn't
+// locked, so call it from init or before serving any connections, not
+// while validators or Help may be reading the table.
+func Register(cmd Command) error {
+	name := cmd.Name
+	if !validCommandName(name) {
+		return errors.New("command names must be uppercase letters")
+	}
+	if reserved[name] {
+		return errors.New(name + " is a reserved protocol keyword")
+	}
+
+	Commands[name] = cmd
+	return nil
+}
+
+// validCommandName is the command grammar the validator enforces on the
+// wire: one or more of A-Z.
+func validCommandName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < 'A' || name[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func IsReserved(name string) bool {
+	return reserved[strings.ToUpper(name)]
+}
+
+func Known(name string) bool {
+	_, ok := Commands[strings.ToUpper(name)]
+	return ok
+}
+
+func UnknownCommand(name string) string {
+	return FormatError(CodeUnknownCommand, name)
+}
+
+func (cmd Command) Usage() string {
+	return strings.TrimSpace(cmd.Name + " " + cmd.Syntax)
+}
+
+func Help(name string, oper bool) []string {
+	visible := func(cmd Command) bool {
+		return !cmd.ServerOnly && (oper || !cmd.Oper)
+	}
+
+	if name != "" {
+		cmd, ok := Commands[strings.ToUpper(name)]
+		if !ok || !visible(cmd) {
+			return nil
+		}
+		return []string{"HELP " + cmd.Usage() + " - " + cmd.Summary}
+	}
+
+	var lines []string
+	for _, cmd := range Commands {
+		if visible(cmd) {
+			lines = append(lines, "HELP "+cmd.Usage()+" - "+cmd.Summary)
+		}
+	}
+	sort.Strings(lines)
+
+	return lines
+}
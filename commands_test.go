@@ -0,0 +1,80 @@
+package vsic
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKnownIgnoresCase(t *testing.T) {
+	for _, name := range []string{"JOIN", "join", "Join"} {
+		if !Known(name) || !IsReserved(name) {
+			t.Errorf("%s: Known=%v IsReserved=%v, want both true", name, Known(name), IsReserved(name))
+		}
+	}
+	if Known("nope") {
+		t.Error("Known(nope) = true")
+	}
+}
+
+func TestHelp(t *testing.T) {
+	if err := Register(Command{Name: "XKILL", Args: 1, Syntax: "<nick>", Summary: "disconnect a user", Oper: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Commands, "XKILL")
+
+	tests := []struct {
+		name  string
+		oper  bool
+		want  []string // lines that must be present
+		never []string // commands that must not show up
+	}{
+		{"", false, []string{"HELP JOIN <channel> - join a channel"}, []string{"XKILL", "NOTICE", "ERROR", "LIMITS"}},
+		{"", true, []string{"HELP XKILL <nick> - disconnect a user"}, []string{"NOTICE", "ERROR"}},
+		{"join", false, []string{"HELP JOIN <channel> - join a channel"}, nil},
+		{"xkill", false, nil, []string{"XKILL"}},
+		{"xkill", true, []string{"HELP XKILL <nick> - disconnect a user"}, nil},
+		{"notice", true, nil, []string{"NOTICE"}},
+		{"nope", false, nil, nil},
+	}
+
+	for _, tt := range tests {
+		got := Help(tt.name, tt.oper)
+		if !slices.IsSorted(got) {
+			t.Errorf("Help(%q, %v) not sorted: %q", tt.name, tt.oper, got)
+		}
+		if tt.name != "" && len(got) != len(tt.want) {
+			t.Errorf("Help(%q, %v) = %q, want %q", tt.name, tt.oper, got, tt.want)
+		}
+		for _, w := range tt.want {
+			if !slices.Contains(got, w) {
+				t.Errorf("Help(%q, %v) missing %q", tt.name, tt.oper, w)
+			}
+		}
+		for _, line := range got {
+			for _, n := range tt.never {
+				if strings.HasPrefix(line, "HELP "+n+" ") || line == "HELP "+n {
+					t.Errorf("Help(%q, %v) lists %s", tt.name, tt.oper, n)
+				}
+			}
+		}
+	}
+}
+
+func TestRegisterMatchesValidator(t *testing.T) {
+	defer delete(Commands, "XTEST")
+
+	for _, name := range []string{"X1", "FOO_BAR", "xtest", ""} {
+		if err := Register(Command{Name: name}); err == nil {
+			delete(Commands, name)
+			t.Errorf("Register(%q) accepted a name the validator rejects", name)
+		}
+	}
+
+	if err := Register(Command{Name: "XTEST"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewValidator(0, nil).Check("XTEST"); err != nil {
+		t.Fatalf("registered command rejected: %v", err)
+	}
+}
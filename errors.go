@@ -0,0 +1,92 @@
+package vsic
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// error codes sent as ERROR <code> <text>. servers should send one before
+// closing a connection on their own so clients can tell why it happened.
+const (
+	CodeProtocol       = 400
+	CodeBanned         = 403
+	CodeTimeout        = 408
+	CodeKicked         = 410
+	CodeTooBig         = 413
+	CodeUnknownCommand = 421
+	CodeRateLimit      = 429
+	CodeBadNick        = 432
+	CodeNickInUse      = 433
+	CodeUnexpected     = 500
+	CodeShutdown       = 503
+)
+
+var codeReasons = map[int]string{
+	CodeProtocol:       "protocol error",
+	CodeBanned:         "banned",
+	CodeTimeout:        "timed out",
+	CodeKicked:         "kicked",
+	CodeTooBig:         "message too big",
+	CodeUnknownCommand: "unknown command",
+	CodeRateLimit:      "rate limited",
+	CodeNickInUse:      "nick in use",
+	CodeBadNick:        "invalid nick",
+	CodeShutdown:       "server shutting down",
+	CodeUnexpected:     "internal error",
+}
+
+type ServerError struct {
+	Code       int
+	Text       string
+	RetryAfter time.Duration
+}
+
+func (e *ServerError) Error() string {
+	if e.Code == 0 {
+		return "server error: " + e.Text
+	}
+	return "server error " + strconv.Itoa(e.Code) + ": " + e.Text
+}
+
+func FormatError(code int, text string) string {
+	if text == "" {
+		text = codeReasons[code]
+	}
+	return strings.TrimSpace("ERROR " + strconv.Itoa(code) + " " + text)
+}
+
+// FormatRetryError is FormatError for rejections the client can retry,
+// e.g. ERROR 429 retry-after=5 rate limited.
+func FormatRetryError(code int, retryAfter time.Duration, text string) string {
+	if text == "" {
+		text = codeReasons[code]
+	}
+	secs := int((retryAfter + time.Second - 1) / time.Second)
+	return strings.TrimSpace("ERROR " + strconv.Itoa(code) + " retry-after=" + strconv.Itoa(secs) + " " + text)
+}
+
+func ParseError(arg string) *ServerError {
+	head, text, _ := strings.Cut(arg, " ")
+	code, err := strconv.Atoi(head)
+	if err != nil {
+		return &ServerError{Text: arg}
+	}
+
+	e := &ServerError{Code: code, Text: text}
+
+	if v, ok := strings.CutPrefix(text, "retry-after="); ok {
+		secs, rest, _ := strings.Cut(v, " ")
+		if n, err := strconv.Atoi(secs); err == nil && n >= 0 {
+			e.RetryAfter = time.Duration(n) * time.Second
+			e.Text = rest
+		}
+	}
+
+	return e
+}
+
+func (c *Conn) CloseWithError(code int, text string) error {
+	_ = c.WriteLine(FormatError(code, text))
+	return c.Close()
+}
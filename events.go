@@ -0,0 +1,198 @@
+package vsic
+
+import (
+	"errors"
+	"time"
+)
+
+type Event interface {
+	event()
+}
+
+type StateEvent struct {
+	From, To State
+}
+
+type ConnectedEvent struct {
+	Nick string
+}
+
+type MOTDEvent struct {
+	Text string
+}
+
+type MessageEvent struct {
+	From, Target, Text string
+}
+
+type ErrorEvent struct {
+	Err *ServerError
+}
+
+type LineEvent struct {
+	Cmd, Arg string
+}
+
+// DisconnectedEvent comes once, just before the client reaches
+// StateClosed. Err is nil after Quit.
+type DisconnectedEvent struct {
+	Err error
+}
+
+func (StateEvent) event()        {}
+func (ConnectedEvent) event()    {}
+func (MOTDEvent) event()         {}
+func (MessageEvent) event()      {}
+func (ErrorEvent) event()        {}
+func (LineEvent) event()         {}
+func (DisconnectedEvent) event() {}
+
+// Events starts reading the connection and returns a channel of typed
+// events. it takes over reading, so don't call Recv once it's been used.
+// the channel is closed when the client reaches StateClosed.
+func (c *Client) Events() <-chan Event {
+	c.eventsOnce.Do(func() {
+		c.mu.Lock()
+		c.reported = c.state
+		c.mu.Unlock()
+
+		c.events = make(chan Event, 64)
+		go c.eventLoop()
+	})
+	return c.events
+}
+
+// transition moves to s and reports it on the events channel. once closed
+// (e.g. by Quit from another goroutine) the client stays closed. Quit only
+// sets the state, since the event loop is the one goroutine that sends on
+// events, so From is the last state reported rather than c.state.
+func (c *Client) transition(s State) bool {
+	c.mu.Lock()
+	if c.state == StateClosed && s != StateClosed {
+		c.mu.Unlock()
+		return false
+	}
+	c.state = s
+	from := c.reported
+	c.reported = s
+	c.mu.Unlock()
+
+	if from != s {
+		c.events <- StateEvent{From: from, To: s}
+	}
+	return true
+}
+
+func (c *Client) eventLoop() {
+	defer close(c.events)
+
+	c.emitRegistered()
+
+	for {
+		line, err := c.Recv()
+		if err != nil {
+			if c.State() != StateClosed && c.AutoResume && c.dial != nil {
+				if err = c.resume(); err == nil {
+					c.emitRegistered()
+					continue
+				}
+			}
+
+			// a Quit isn't an error, whether it came mid-session or
+			// while resuming
+			if c.State() == StateClosed {
+				err = nil
+			}
+			c.events <- DisconnectedEvent{Err: err}
+			c.transition(StateClosed)
+			return
+		}
+
+		c.events <- lineEvent(line)
+	}
+}
+
+// emitRegistered announces a fresh registration; NOTICE lines the server
+// sent before its HELLO reply are the MOTD.
+func (c *Client) emitRegistered() {
+	c.events <- ConnectedEvent{Nick: c.conn().Nick}
+
+	for _, line := range c.pending {
+		if cmd, arg := ParseCommand(line); cmd == "NOTICE" {
+			c.events <- MOTDEvent{Text: arg}
+		} else {
+			c.events <- lineEvent(line)
+		}
+	}
+	c.pending = nil
+}
+
+func lineEvent(line string) Event {
+	cmd, arg := ParseCommand(line)
+
+	switch cmd {
+	case "MSG":
+		if from, target, text, ok := ParseMsg(arg); ok {
+			return MessageEvent{From: from, Target: target, Text: text}
+		}
+	case "ERROR":
+		return ErrorEvent{Err: ParseError(arg)}
+	}
+
+	return LineEvent{Cmd: cmd, Arg: arg}
+}
+
+func (c *Client) resume() error {
+	backoff := time.Second
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if !c.transition(StateResuming) {
+			return errClientClosed
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+
+		if !c.transition(StateConnecting) {
+			return errClientClosed
+		}
+		nc, err := c.dial()
+		if err != nil {
+			continue
+		}
+
+		if !c.transition(StateRegistering) {
+			nc.Close()
+			return errClientClosed
+		}
+		conn, pending, err := register(nc, c.nick, c.cfg)
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		old := c.Conn
+		c.Conn = conn
+		channels := make([]string, 0, len(c.channels))
+		for _, ch := range c.channels {
+			channels = append(channels, ch)
+		}
+		c.mu.Unlock()
+		old.Close()
+
+		c.pending = pending
+		c.scanLimits(pending)
+		for _, ch := range channels {
+			if err := conn.WriteLine("JOIN " + ch); err != nil {
+				return err
+			}
+		}
+
+		if !c.transition(StateReady) {
+			conn.Close()
+			return errClientClosed
+		}
+		return nil
+	}
+
+	return errors.New("could not resume session")
+}
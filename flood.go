@@ -0,0 +1,241 @@
+package vsic
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// when SendRate is set, Send/Join/Part go through a queue that is drained
+// at SendRate lines per second with bursts of up to SendBurst, so a chatty
+// bot stays under the server's flood limit instead of being dropped. a
+// server can advertise its limit with LIMITS rate=<n> burst=<n>, which
+// overrides the configured values.
+
+const defaultSendQueue = 256
+
+func (c *Client) SetSendLimit(rate float64, burst int) {
+	c.mu.Lock()
+	c.SendRate = rate
+	c.SendBurst = burst
+	c.mu.Unlock()
+}
+
+// scanLimits picks up a LIMITS line the server sent during registration.
+func (c *Client) scanLimits(lines []string) {
+	for _, line := range lines {
+		if cmd, arg := ParseCommand(line); cmd == "LIMITS" {
+			c.applyLimits(arg)
+		}
+	}
+}
+
+func (c *Client) applyLimits(arg string) {
+	rate, burst := -1.0, -1
+	for _, f := range strings.Fields(arg) {
+		k, v, _ := strings.Cut(f, "=")
+		switch k {
+		case "rate":
+			if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
+				rate = n
+			}
+		case "burst":
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				burst = n
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rate > 0 {
+		c.SendRate = rate
+	}
+	if burst > 0 {
+		c.SendBurst = burst
+	}
+}
+
+// enqueue checks the line up front so a bad one is reported to the caller
+// here rather than failing later in drain, where it would look like the
+// connection broke.
+func (c *Client) enqueue(line string) error {
+	conn := c.conn()
+	if err := conn.checkLine(line); err != nil {
+		return err
+	}
+
+	select {
+	case <-conn.Done():
+		if c.State() == StateClosed || !c.AutoResume {
+			return errClientClosed
+		}
+	default:
+	}
+
+	c.outOnce.Do(func() {
+		size := c.SendQueue
+		if size <= 0 {
+			size = defaultSendQueue
+		}
+		c.mu.Lock()
+		c.outq = make(chan string, size)
+		c.mu.Unlock()
+		go c.drain()
+	})
+
+	c.mu.Lock()
+	err := c.outErr
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	c.outPending.Add(1)
+
+	select {
+	case c.outq <- line:
+		return nil
+	default:
+	}
+
+	if c.OnSaturated != nil {
+		c.OnSaturated(len(c.outq))
+	}
+
+	select {
+	case c.outq <- line:
+		return nil
+	case <-c.conn().Done():
+		c.outPending.Add(-1)
+		return errClientClosed
+	}
+}
+
+// flushQueue gives queued lines up to timeout to go out, so Quit doesn't
+// cut off the tail of what was sent just before it.
+func (c *Client) flushQueue(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for c.outPending.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *Client) drain() {
+	tokens := -1.0
+	last := time.Now()
+
+	for {
+		var line string
+		select {
+		case line = <-c.outq:
+		case <-c.conn().Done():
+			if c.State() == StateClosed || !c.AutoResume {
+				c.stopDrain()
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		c.mu.Lock()
+		rate, burst := c.SendRate, float64(max(c.SendBurst, 1))
+		wait := time.Until(c.holdUntil)
+		conn := c.Conn
+		c.mu.Unlock()
+
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+
+		if rate > 0 {
+			if tokens < 0 {
+				tokens = burst
+			}
+			now := time.Now()
+			tokens = min(burst, tokens+now.Sub(last).Seconds()*rate)
+			last = now
+
+			if tokens < 1 {
+				time.Sleep(time.Duration((1 - tokens) / rate * float64(time.Second)))
+				tokens = 1
+				last = time.Now()
+			}
+			tokens--
+		}
+
+		err := c.writeQueued(conn, line)
+		c.outPending.Add(-1)
+
+		if err != nil {
+			c.mu.Lock()
+			if c.state == StateClosed {
+				c.outErr = errClientClosed
+			} else if c.outErr == nil {
+				c.outErr = err
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// writeQueued sends one queued line. with AutoResume a line that can't go
+// out because the session is down is kept for the resumed one rather than
+// dropped; a write to a connection the server just hung up on can still
+// succeed locally, so it waits whenever the client isn't ready, not only
+// after a failed write.
+func (c *Client) writeQueued(conn *Conn, line string) error {
+	for {
+		if c.AutoResume && c.State() != StateReady {
+			if conn = c.waitReady(nil); conn == nil {
+				return errClientClosed
+			}
+		}
+
+		err := conn.WriteLine(line)
+		if err == nil || !c.AutoResume {
+			return err
+		}
+
+		if conn = c.waitReady(conn); conn == nil {
+			return errClientClosed
+		}
+	}
+}
+
+// waitReady waits for the client to be ready on a connection other than
+// failed and returns it, or nil once the client is closed.
+func (c *Client) waitReady(failed *Conn) *Conn {
+	for {
+		c.mu.Lock()
+		state, conn := c.state, c.Conn
+		c.mu.Unlock()
+
+		if state == StateClosed {
+			return nil
+		}
+		if state == StateReady && conn != failed {
+			return conn
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// stopDrain fails later sends and drops whatever is still queued, so
+// nothing waits on lines that will never go out.
+func (c *Client) stopDrain() {
+	c.mu.Lock()
+	if c.outErr == nil {
+		c.outErr = errClientClosed
+	}
+	c.mu.Unlock()
+
+	for {
+		select {
+		case <-c.outq:
+			c.outPending.Add(-1)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package vsic
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// nicks are ASCII-only (see ValidNick), so they fold by lowering A-Z.
+// channel names can carry any UTF-8 and fold rune by rune to one member of
+// each unicode case-folding orbit, so names strings.EqualFold considers
+// equal always fold the same (ς, σ and Σ are one channel).
+// servers should key every nick and channel lookup on the folded form.
+
+func FoldNick(n string) string {
+	for i := 0; i < len(n); i++ {
+		if n[i] >= 'A' && n[i] <= 'Z' {
+			b := []byte(n)
+			for j := i; j < len(b); j++ {
+				if b[j] >= 'A' && b[j] <= 'Z' {
+					b[j] += 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return n
+}
+
+func FoldChannel(ch string) string {
+	for i := 0; i < len(ch); i++ {
+		if ch[i] >= utf8.RuneSelf {
+			return strings.Map(foldRune, ch)
+		}
+	}
+	return FoldNick(ch)
+}
+
+// foldRune picks the smallest rune in r's unicode.SimpleFold orbit, lowered
+// if it's ASCII so plain names fold the same as with FoldNick (the Kelvin
+// sign ends up as k).
+func foldRune(r rune) rune {
+	least := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		least = min(least, f)
+	}
+	if least >= 'A' && least <= 'Z' {
+		least += 'a' - 'A'
+	}
+	return least
+}
+
+func EqualNick(a, b string) bool {
+	return len(a) == len(b) && FoldNick(a) == FoldNick(b)
+}
+
+func EqualChannel(a, b string) bool {
+	return FoldChannel(a) == FoldChannel(b)
+}
@@ -0,0 +1,57 @@
+package vsic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"#General", "#general", true},
+		{"#GENERAL", "#general", true},
+		{"#ς", "#Σ", true},
+		{"#σ", "#Σ", true},
+		{"#Straße", "#STRAßE", true},
+		{"#K", "#k", true}, // kelvin sign
+		{"#ſ", "#S", true}, // long s
+		{"#Ǆ", "#ǅ", true},
+		{"#a", "#b", false},
+		{"#ä", "#a", false},
+		{"#general", "#general2", false},
+	}
+
+	for _, tt := range tests {
+		if got := EqualChannel(tt.a, tt.b); got != tt.equal {
+			t.Errorf("EqualChannel(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+		if got := strings.EqualFold(tt.a, tt.b); got != tt.equal {
+			t.Errorf("strings.EqualFold(%q, %q) = %v, table is wrong", tt.a, tt.b, got)
+		}
+	}
+
+	if got := FoldChannel("#General"); got != "#general" {
+		t.Errorf("FoldChannel(#General) = %q, want #general", got)
+	}
+}
+
+func TestFoldNick(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"alice", "alice"},
+		{"Alice", "alice"},
+		{"ALICE_99", "alice_99"},
+	}
+
+	for _, tt := range tests {
+		if got := FoldNick(tt.in); got != tt.want {
+			t.Errorf("FoldNick(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if !EqualNick("Bob", "bOB") || EqualNick("bob", "bobby") {
+		t.Error("EqualNick disagrees with FoldNick")
+	}
+}
@@ -0,0 +1,87 @@
+package vsic
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type Frame struct {
+	b []byte
+}
+
+func NewFrame(s string) (Frame, error) {
+	if strings.ContainsAny(s, "\n\r") {
+		return Frame{}, errors.New("invalid control chars")
+	}
+
+	b := make([]byte, len(s)+1)
+	copy(b, s)
+	b[len(s)] = '\n'
+
+	return Frame{b: b}, nil
+}
+
+func (f Frame) String() string {
+	if len(f.b) == 0 {
+		return ""
+	}
+	return string(f.b[:len(f.b)-1])
+}
+
+func (f Frame) Len() int {
+	if len(f.b) == 0 {
+		return 0
+	}
+	return len(f.b) - 1
+}
+
+func (c *Conn) WriteFrame(f Frame) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err := c.queueFrame(f); err != nil {
+		return err
+	}
+
+	return c.W.Flush()
+}
+
+// QueueFrame is QueueLine for a pre-built frame.
+func (c *Conn) QueueFrame(f Frame) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err := c.queueFrame(f); err != nil {
+		return err
+	}
+
+	return c.maybeFlush()
+}
+
+func (c *Conn) queueFrame(f Frame) error {
+	if len(f.b) == 0 {
+		return errors.New("empty frame")
+	}
+	if c.W == nil {
+		return errors.New("connection released")
+	}
+	if c.W.Buffered() == 0 {
+		c.queuedAt = time.Now()
+	}
+
+	_ = c.NetConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+	if f.Len() > c.cfg.MaxMsgSize {
+		return errors.New("message too big")
+	}
+
+	if c.v.Load() != nil {
+		if err := c.validate(f.String(), "outbound"); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.W.Write(f.b)
+	return err
+}
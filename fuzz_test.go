@@ -0,0 +1,118 @@
+package vsic
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+type fuzzConn struct {
+	*bytes.Reader
+}
+
+func (fuzzConn) Write(b []byte) (int, error)      { return len(b), nil }
+func (fuzzConn) Close() error                     { return nil }
+func (fuzzConn) LocalAddr() net.Addr              { return &net.TCPAddr{} }
+func (fuzzConn) RemoteAddr() net.Addr             { return &net.TCPAddr{} }
+func (fuzzConn) SetDeadline(time.Time) error      { return nil }
+func (fuzzConn) SetReadDeadline(time.Time) error  { return nil }
+func (fuzzConn) SetWriteDeadline(time.Time) error { return nil }
+
+var fuzzSeeds = []string{
+	"",
+	"HELLO bob",
+	"MSG #chan hello world",
+	"MSG #chan  two  spaces ",
+	"PING",
+	" LEADING",
+	"lone\rcarriage",
+	"crlf\r\n",
+	"\r",
+	"\x00\x01\x7f",
+	"ERROR 429 retry-after=5 rate limited",
+	"ERROR retry-after=x",
+	"nick\xff\xfe",
+	"ünïcödé ☃ ‮",
+	strings.Repeat("A", 5000),
+}
+
+func FuzzParseCommand(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		cmd, arg := ParseCommand(line)
+		if strings.Contains(cmd, " ") {
+			t.Fatalf("command %q contains a space", cmd)
+		}
+		if len(cmd)+len(arg) > len(line) {
+			t.Fatalf("parsed more than the input: %q %q", cmd, arg)
+		}
+
+		ParseMsg(arg)
+		ParseError(arg)
+	})
+}
+
+func FuzzReadLine(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s + "\n"))
+	}
+	f.Add([]byte("a\nb\r\nc\n"))
+	f.Add([]byte("no newline"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c := Wrap(fuzzConn{bytes.NewReader(data)}, Config{MaxMsgSize: 512})
+
+		for {
+			line, err := c.ReadLine()
+			if err != nil {
+				return
+			}
+			if len(line) > 512 {
+				t.Fatalf("line of %d bytes exceeds MaxMsgSize", len(line))
+			}
+			if strings.ContainsAny(line, "\r\n") {
+				t.Fatalf("line %q contains control chars", line)
+			}
+		}
+	})
+}
+
+func FuzzValidNick(f *testing.F) {
+	for _, s := range []string{"bob", "ab", "a_b_c", "ünï", strings.Repeat("x", 21), "spa ce", "_0000"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, nick string) {
+		if !ValidNick(nick) {
+			return
+		}
+		if len(nick) < 3 || len(nick) > 20 || !utf8.ValidString(nick) || strings.ContainsAny(nick, " \r\n") {
+			t.Fatalf("ValidNick accepted %q", nick)
+		}
+	})
+}
+
+func FuzzValidator(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+
+	v := NewValidator(512, []string{"payload"})
+	f.Fuzz(func(t *testing.T, line string) {
+		if v.Check(line) != nil {
+			return
+		}
+		if !utf8.ValidString(line) || strings.ContainsAny(line, "\r\n") || len(line) > 512 {
+			t.Fatalf("validator accepted %q", line)
+		}
+		if _, ok := Commands[strings.SplitN(line, " ", 2)[0]]; !ok {
+			t.Fatalf("validator accepted unknown command in %q", line)
+		}
+	})
+}
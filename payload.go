@@ -0,0 +1,294 @@
+package vsic
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// payloads are sent as a PAYLOAD <size> header followed by base64 CHUNK lines.
+// the receiver acks every payloadWindow chunks with PAYLOAD ACK <bytes> so a
+// fast sender can't run arbitrarily far ahead of a slow reader.
+//
+// other lines can arrive in the middle of a transfer. the receiving side
+// sets them aside for the next ReadLine (so a PING is only answered once
+// the payload is done), and acks are picked out of whatever is reading the
+// connection, so WritePayload works alongside a Client's Recv/Events loop.
+const payloadWindow = 16
+
+// maxHeld caps how many lines a transfer will set aside before giving up.
+const maxHeld = 256
+
+func (c *Conn) payloadChunkSize() int {
+	n := (c.cfg.MaxMsgSize - len("CHUNK ") - 1) / 4 * 3
+	if n <= 0 {
+		return 0
+	}
+	return n
+}
+
+func (c *Conn) WritePayload(r io.Reader, size int64) error {
+	if size < 0 {
+		return errors.New("invalid payload size")
+	}
+
+	chunk := c.payloadChunkSize()
+	if chunk == 0 {
+		return errors.New("max message size too small for payload")
+	}
+
+	c.pmu.Lock()
+	defer c.pmu.Unlock()
+
+	select {
+	case <-c.acks:
+	default:
+	}
+	c.acking.Store(true)
+	defer c.acking.Store(false)
+
+	if err := c.WriteLine("PAYLOAD " + strconv.FormatInt(size, 10)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunk)
+	var sent int64
+	chunks := 0
+
+	for sent < size {
+		n := int64(chunk)
+		if size-sent < n {
+			n = size - sent
+		}
+
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return err
+		}
+
+		if err := c.WriteLine("CHUNK " + base64.StdEncoding.EncodeToString(buf[:n])); err != nil {
+			return err
+		}
+		sent += n
+		chunks++
+
+		if chunks%payloadWindow == 0 && sent < size {
+			if err := c.waitPayloadAck(sent); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Conn) waitPayloadAck(want int64) error {
+	timeout := time.NewTimer(time.Duration(c.cfg.TimeoutSec) * time.Second)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case got := <-c.acks:
+			return checkAck(got, want)
+		default:
+		}
+
+		// nobody else is reading, so look for the ack ourselves
+		if c.rmu.TryLock() {
+			line, ok, err := c.pollLine()
+			if ok {
+				if got, isAck := parseAck(line); isAck {
+					c.rmu.Unlock()
+					return checkAck(got, want)
+				}
+				err = c.hold(line)
+			}
+			c.rmu.Unlock()
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+		}
+
+		select {
+		case got := <-c.acks:
+			return checkAck(got, want)
+		case <-c.done:
+			return errors.New("connection closed")
+		case <-timeout.C:
+			return errors.New("payload ack timed out")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func parseAck(line string) (int64, bool) {
+	cmd, arg := ParseCommand(line)
+	if cmd != "PAYLOAD" || !strings.HasPrefix(arg, "ACK ") {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(strings.TrimPrefix(arg, "ACK "), 10, 64)
+	if err != nil {
+		return -1, true
+	}
+	return n, true
+}
+
+func checkAck(got, want int64) error {
+	if got != want {
+		return errors.New("bad payload ack")
+	}
+	return nil
+}
+
+// pollLine is readLine for reading on someone else's behalf: with nothing
+// to read it gives up after a moment instead of blocking, so it doesn't
+// keep rmu from a ReadPayload that has to run before the ack can come.
+// callers hold rmu.
+func (c *Conn) pollLine() (string, bool, error) {
+	if c.R == nil {
+		return "", false, errors.New("connection released")
+	}
+
+	if c.R.Buffered() == 0 {
+		_ = c.NetConn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		if _, err := c.R.Peek(1); err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+	}
+
+	line, err := c.readLine()
+	return line, err == nil, err
+}
+
+// nextLine reads a line off the wire, handing payload acks to a waiting
+// WritePayload instead of returning them. callers hold rmu.
+func (c *Conn) nextLine() (string, error) {
+	for {
+		line, err := c.readLine()
+		if err != nil || !c.acking.Load() {
+			return line, err
+		}
+
+		got, ok := parseAck(line)
+		if !ok {
+			return line, nil
+		}
+		select {
+		case c.acks <- got:
+		default:
+		}
+	}
+}
+
+// hold sets a line aside for the next ReadLine. callers hold rmu.
+func (c *Conn) hold(line string) error {
+	if len(c.held) >= maxHeld {
+		return errors.New("too many lines during payload")
+	}
+	c.held = append(c.held, line)
+	return nil
+}
+
+// readCommand reads until a cmd line turns up, holding anything else. a
+// matching line may already be held, e.g. a peer's CHUNK that came in
+// while our own WritePayload was waiting for an ack.
+func (c *Conn) readCommand(cmd string) (string, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	for i, line := range c.held {
+		if got, arg := ParseCommand(line); got == cmd {
+			c.held = append(c.held[:i], c.held[i+1:]...)
+			return arg, nil
+		}
+	}
+
+	for {
+		line, err := c.nextLine()
+		if err != nil {
+			return "", err
+		}
+
+		if got, arg := ParseCommand(line); got == cmd {
+			return arg, nil
+		}
+		if err := c.hold(line); err != nil {
+			return "", err
+		}
+	}
+}
+
+type Payload struct {
+	Size int64
+
+	c      *Conn
+	read   int64
+	chunks int
+	buf    []byte
+}
+
+func (c *Conn) ReadPayload() (*Payload, error) {
+	arg, err := c.readCommand("PAYLOAD")
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || size < 0 {
+		return nil, errors.New("invalid payload size")
+	}
+
+	return &Payload{Size: size, c: c}, nil
+}
+
+func (p *Payload) Read(b []byte) (int, error) {
+	if len(p.buf) == 0 {
+		if p.read >= p.Size {
+			return 0, io.EOF
+		}
+		if err := p.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+func (p *Payload) next() error {
+	arg, err := p.c.readCommand("CHUNK")
+	if err != nil {
+		return err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(arg)
+	if err != nil || len(data) == 0 {
+		return errors.New("invalid payload chunk")
+	}
+
+	if int64(len(data)) > p.Size-p.read {
+		return errors.New("payload overrun")
+	}
+
+	p.read += int64(len(data))
+	p.chunks++
+	p.buf = data
+
+	if p.chunks%payloadWindow == 0 && p.read < p.Size {
+		return p.c.WriteLine("PAYLOAD ACK " + strconv.FormatInt(p.read, 10))
+	}
+
+	return nil
+}
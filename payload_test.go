@@ -0,0 +1,191 @@
+package vsic
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadPayloadInterleaved(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{})
+	defer c.Close()
+
+	go a.Write([]byte("PING\nPAYLOAD 5\nMSG bob #x hi\nCHUNK aGVsbG8=\nPING two\n"))
+
+	p, err := c.ReadPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("payload = %q, want hello", data)
+	}
+
+	for _, want := range []string{"PING", "MSG bob #x hi", "PING two"} {
+		line, err := c.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Fatalf("got %q, want %q", line, want)
+		}
+	}
+}
+
+// both sides send at once. a only starts reading once its own send is
+// done, so b's header and chunks arrive while a's WritePayload is waiting
+// for acks and have to be picked up by the ReadPayload after it.
+func TestPayloadBothWays(t *testing.T) {
+	a, b := tcpPair(t)
+	ca := Wrap(a, Config{TimeoutSec: 5})
+	cb := Wrap(b, Config{TimeoutSec: 5})
+	defer ca.Close()
+	defer cb.Close()
+
+	want := make([]byte, 300_000)
+	rand.Read(want)
+
+	read := func(c *Conn) error {
+		p, err := c.ReadPayload()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, want) {
+			return errors.New("payload mismatch")
+		}
+		return nil
+	}
+	write := func(c *Conn) error {
+		return c.WritePayload(bytes.NewReader(want), int64(len(want)))
+	}
+
+	errc := make(chan error, 2)
+	go func() { errc <- write(cb) }()
+	go func() { errc <- read(cb) }()
+
+	if err := write(ca); err != nil {
+		t.Fatal(err)
+	}
+	if err := read(ca); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// the receiver sends chatter in between its acks. with a concurrent
+// reader (the way a Client's Recv loop would run) acks have to be picked
+// out of its stream; without one WritePayload reads them itself.
+func TestPayloadRoundTrip(t *testing.T) {
+	t.Run("reader", func(t *testing.T) { testPayloadRoundTrip(t, true) })
+	t.Run("no reader", func(t *testing.T) { testPayloadRoundTrip(t, false) })
+}
+
+func testPayloadRoundTrip(t *testing.T, reader bool) {
+	a, b := tcpPair(t)
+	sender := Wrap(a, Config{})
+	receiver := Wrap(b, Config{})
+	defer sender.Close()
+	defer receiver.Close()
+
+	want := make([]byte, 200_000)
+	rand.Read(want)
+
+	lines := make(chan string, maxHeld)
+	readLines := func() {
+		defer close(lines)
+		for {
+			line, err := sender.ReadLine()
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}
+	if reader {
+		go readLines()
+	}
+
+	got := make(chan []byte, 1)
+	go func() {
+		p, err := receiver.ReadPayload()
+		if err != nil {
+			t.Error(err)
+			got <- nil
+			return
+		}
+
+		var buf bytes.Buffer
+		for {
+			_ = receiver.WriteLine("PING")
+			n, err := io.CopyN(&buf, p, 4096)
+			if err == io.EOF || n == 0 {
+				break
+			}
+			if err != nil {
+				t.Error(err)
+				break
+			}
+		}
+		got <- buf.Bytes()
+	}()
+
+	if err := sender.WritePayload(bytes.NewReader(want), int64(len(want))); err != nil {
+		t.Fatal(err)
+	}
+	if data := <-got; !bytes.Equal(data, want) {
+		t.Fatalf("payload mismatch: got %d bytes, want %d", len(data), len(want))
+	}
+
+	receiver.Close()
+	if !reader {
+		go readLines()
+	}
+
+	pings := 0
+	for line := range lines {
+		if strings.HasPrefix(line, "PAYLOAD") {
+			t.Fatalf("reader saw %q", line)
+		}
+		pings++
+	}
+	if pings == 0 {
+		t.Fatal("none of the interleaved lines came through")
+	}
+}
+
+// tcpPair is net.Pipe with real socket buffers, so a side that isn't
+// reading doesn't block the other side's writes.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	a, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
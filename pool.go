@@ -0,0 +1,48 @@
+package vsic
+
+import (
+	"bufio"
+	"sync"
+)
+
+// an idle connection holds one read and one write buffer, so with the
+// sizes below that's ~5KB per connection on top of the Conn itself. lines
+// longer than readBufSize are still accepted up to MaxMsgSize, they just
+// get assembled outside the buffer.
+const (
+	readBufSize  = 1024
+	writeBufSize = 4096
+)
+
+var (
+	readerPool = sync.Pool{New: func() any { return bufio.NewReaderSize(nil, readBufSize) }}
+	writerPool = sync.Pool{New: func() any { return bufio.NewWriterSize(nil, writeBufSize) }}
+)
+
+// Release closes the connection and hands its buffers back to the pool.
+// a ReadLine blocked on the connection returns first, and reads or writes
+// after it get an error, so it's safe to call with a reader still running.
+func (c *Conn) Release() {
+	c.Close()
+
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+	}
+	c.held = nil
+
+	if c.R != nil {
+		c.R.Reset(nil)
+		readerPool.Put(c.R)
+		c.R = nil
+	}
+	if c.W != nil {
+		c.W.Reset(nil)
+		writerPool.Put(c.W)
+		c.W = nil
+	}
+}
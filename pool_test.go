@@ -0,0 +1,67 @@
+package vsic
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentClose(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			c.Close()
+			<-c.Done()
+		})
+	}
+	wg.Wait()
+}
+
+func TestReleaseWhileReading(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{})
+
+	read := make(chan error)
+	go func() {
+		_, err := c.ReadLine()
+		read <- err
+	}()
+
+	c.Release()
+	if err := <-read; err == nil {
+		t.Fatal("blocked read succeeded after Release")
+	}
+	if _, err := c.ReadLine(); err == nil {
+		t.Fatal("read after Release succeeded")
+	}
+}
+
+func TestWriteAfterRelease(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{})
+	f, _ := NewFrame("PING")
+
+	var wg sync.WaitGroup
+	wg.Go(c.Release)
+	for range 4 {
+		wg.Go(func() {
+			c.QueueLine("PING")
+			c.QueueFrame(f)
+			c.Flush()
+		})
+	}
+	wg.Wait()
+
+	if err := c.WriteLine("PING"); err == nil {
+		t.Fatal("WriteLine after Release succeeded")
+	}
+	if err := c.QueueFrame(f); err == nil {
+		t.Fatal("QueueFrame after Release succeeded")
+	}
+}
@@ -0,0 +1,179 @@
+package testutil
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/initframs/vsic"
+)
+
+type Step struct {
+	expect string
+	prefix bool
+	send   string
+	delay  time.Duration
+	close  bool
+}
+
+type Script []Step
+
+func Expect(line string) Step {
+	return Step{expect: line}
+}
+
+func ExpectPrefix(prefix string) Step {
+	return Step{expect: prefix, prefix: true}
+}
+
+func Send(line string) Step {
+	return Step{send: line}
+}
+
+func After(d time.Duration, line string) Step {
+	return Step{send: line, delay: d}
+}
+
+func Sleep(d time.Duration) Step {
+	return Step{delay: d}
+}
+
+func Hangup() Step {
+	return Step{close: true}
+}
+
+type MockServer struct {
+	Addr string
+
+	t       testing.TB
+	l       net.Listener
+	cfg     vsic.Config
+	scripts []Script
+	wg      sync.WaitGroup
+	running sync.WaitGroup
+
+	mu      sync.Mutex
+	conns   int
+	active  []*vsic.Conn
+	closing bool
+}
+
+func StartMockServer(t testing.TB, cfg vsic.Config, scripts ...Script) *MockServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("mock server: %v", err)
+	}
+
+	s := &MockServer{Addr: l.Addr().String(), t: t, l: l, cfg: cfg, scripts: scripts}
+	s.wg.Add(1)
+	go s.accept()
+
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func (s *MockServer) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+// Close stops accepting, gives running scripts a moment to finish and
+// then hangs up on any connection still open.
+func (s *MockServer) Close() {
+	s.l.Close()
+
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+	}
+
+	s.mu.Lock()
+	for _, c := range s.active {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *MockServer) accept() {
+	defer s.wg.Done()
+
+	for {
+		nc, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+
+		c := vsic.Wrap(nc, s.cfg)
+
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			c.Close()
+			continue
+		}
+		i := s.conns
+		s.conns++
+		s.active = append(s.active, c)
+		if i < len(s.scripts) {
+			s.wg.Add(1)
+			s.running.Add(1)
+		}
+		s.mu.Unlock()
+
+		if i >= len(s.scripts) {
+			s.t.Errorf("mock server: unexpected connection #%d", i+1)
+			c.Close()
+			continue
+		}
+
+		go s.run(i, c, s.scripts[i])
+	}
+}
+
+func (s *MockServer) run(i int, c *vsic.Conn, script Script) {
+	defer s.wg.Done()
+	defer s.running.Done()
+	defer c.Close()
+
+	for n, step := range script {
+		if step.delay > 0 {
+			time.Sleep(step.delay)
+		}
+
+		switch {
+		case step.close:
+			return
+		case step.send != "":
+			if err := c.WriteLine(step.send); err != nil {
+				s.t.Errorf("mock server: conn #%d step %d: send %q: %v", i+1, n+1, step.send, err)
+				return
+			}
+		case step.expect != "":
+			line, err := c.ReadLine()
+			if err != nil {
+				s.t.Errorf("mock server: conn #%d step %d: expected %q, got error: %v", i+1, n+1, step.expect, err)
+				return
+			}
+			if step.prefix && !strings.HasPrefix(line, step.expect) || !step.prefix && line != step.expect {
+				s.t.Errorf("mock server: conn #%d step %d: expected %q, got %q", i+1, n+1, step.expect, line)
+				return
+			}
+		}
+	}
+}
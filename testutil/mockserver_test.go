@@ -0,0 +1,76 @@
+package testutil
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/initframs/vsic"
+)
+
+// recorder keeps the mock server's failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	mu   sync.Mutex
+	errs []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, format)
+}
+
+func dialMock(t *testing.T, addr string) *vsic.Conn {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vsic.Wrap(nc, vsic.Config{})
+}
+
+func TestMockServerScript(t *testing.T) {
+	rec := &recorder{TB: t}
+	srv := StartMockServer(rec, vsic.Config{}, Script{
+		Expect("HELLO a"),
+		Send("HELLO a"),
+		ExpectPrefix("MSG #x"),
+		Hangup(),
+	})
+
+	c := dialMock(t, srv.Addr)
+	defer c.Close()
+	c.WriteLine("HELLO a")
+	if line, err := c.ReadLine(); err != nil || line != "HELLO a" {
+		t.Fatalf("got %q, %v", line, err)
+	}
+	c.WriteLine("MSG #x hi")
+	if _, err := c.ReadLine(); err == nil {
+		t.Fatal("connection still open after Hangup")
+	}
+
+	srv.Close()
+	if len(rec.errs) != 0 {
+		t.Fatalf("unexpected failures: %v", rec.errs)
+	}
+}
+
+func TestMockServerReportsMismatch(t *testing.T) {
+	rec := &recorder{TB: t}
+	srv := StartMockServer(rec, vsic.Config{}, Script{Expect("HELLO a")})
+
+	c := dialMock(t, srv.Addr)
+	defer c.Close()
+	c.WriteLine("HELLO b")
+	c.ReadLine()
+
+	extra := dialMock(t, srv.Addr)
+	defer extra.Close()
+	extra.ReadLine()
+
+	srv.Close()
+	if len(rec.errs) != 2 || !strings.Contains(rec.errs[0], "expected") || !strings.Contains(rec.errs[1], "unexpected connection") {
+		t.Fatalf("failures = %q, want a mismatch and an unexpected connection", rec.errs)
+	}
+}
@@ -0,0 +1,144 @@
+package vsic
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Violation struct {
+	Line   string
+	Dir    string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	if v.Dir == "" {
+		return "protocol violation: " + v.Reason + ": " + strconv.Quote(v.Line)
+	}
+	return "protocol violation (" + v.Dir + "): " + v.Reason + ": " + strconv.Quote(v.Line)
+}
+
+// Role is which end of the connection a Validator checks for. it decides
+// the direction server-only commands are allowed to travel; the zero value
+// doesn't check direction at all.
+type Role int
+
+const (
+	RoleAny Role = iota
+	RoleClient
+	RoleServer
+)
+
+type Validator struct {
+	MaxLen int
+	Caps   map[string]bool
+	Role   Role
+}
+
+func NewValidator(maxLen int, caps []string) *Validator {
+	v := &Validator{MaxLen: maxLen, Caps: map[string]bool{}}
+	for _, c := range caps {
+		v.Caps[c] = true
+	}
+	return v
+}
+
+func (v *Validator) Check(line string) error {
+	if reason := v.check(line, ""); reason != "" {
+		return &Violation{Line: line, Reason: reason}
+	}
+	return nil
+}
+
+func (v *Validator) check(line, dir string) string {
+	if line == "" {
+		return "empty line"
+	}
+	if v.MaxLen > 0 && len(line) > v.MaxLen {
+		return "line exceeds " + strconv.Itoa(v.MaxLen) + " bytes"
+	}
+	if !utf8.ValidString(line) {
+		return "invalid utf-8"
+	}
+	for i, r := range line {
+		if unicode.IsControl(r) {
+			return "control character at byte " + strconv.Itoa(i)
+		}
+	}
+
+	cmd, rest, hasArgs := strings.Cut(line, " ")
+	if cmd == "" {
+		return "leading space"
+	}
+	if !validCommandName(cmd) {
+		return "command must be uppercase letters"
+	}
+
+	def, ok := Commands[cmd]
+	if !ok {
+		return "unknown command " + cmd
+	}
+	if def.ServerOnly && v.fromClient(dir) {
+		return cmd + " is sent by servers only"
+	}
+	if def.Cap != "" && !v.Caps[def.Cap] {
+		return cmd + " requires capability " + def.Cap
+	}
+
+	if hasArgs && (rest == "" || rest[0] == ' ') {
+		return "empty parameter"
+	}
+
+	n := 0
+	if hasArgs {
+		n = len(strings.SplitN(rest, " ", def.Args+1))
+	}
+	if n < def.Args {
+		return cmd + " needs " + strconv.Itoa(def.Args) + " parameter(s)"
+	}
+
+	return ""
+}
+
+// fromClient reports whether a line going dir was sent by the client end.
+func (v *Validator) fromClient(dir string) bool {
+	switch v.Role {
+	case RoleClient:
+		return dir == "outbound"
+	case RoleServer:
+		return dir == "inbound"
+	}
+	return false
+}
+
+func (c *Conn) validate(line, dir string) error {
+	v := c.v.Load()
+	if v == nil {
+		return nil
+	}
+	if reason := v.check(line, dir); reason != "" {
+		return &Violation{Line: line, Dir: dir, Reason: reason}
+	}
+	return nil
+}
+
+// EnableCap adds a capability negotiated after Wrap, so strict mode
+// accepts its commands from here on. it's a no-op without Strict.
+func (c *Conn) EnableCap(name string) {
+	for {
+		old := c.v.Load()
+		if old == nil || old.Caps[name] {
+			return
+		}
+
+		v := &Validator{MaxLen: old.MaxLen, Caps: map[string]bool{name: true}, Role: old.Role}
+		for k := range old.Caps {
+			v.Caps[k] = true
+		}
+		if c.v.CompareAndSwap(old, v) {
+			return
+		}
+	}
+}
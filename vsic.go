@@ -7,14 +7,25 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const Version = "0.1.0"
 
 type Config struct {
-	MaxMsgSize int
-	TimeoutSec int
+	MaxMsgSize   int
+	TimeoutSec   int
+	HandshakeSec int
+	Strict       bool
+	Role         Role
+	Caps         []string
+
+	CoalesceBytes int
+	CoalesceMs    int
+
+	Clock Clock
 }
 
 type Conn struct {
@@ -23,6 +34,28 @@ type Conn struct {
 	W       *bufio.Writer
 	Nick    string
 	cfg     Config
+	v       atomic.Pointer[Validator]
+
+	wmu        sync.Mutex
+	queuedAt   time.Time
+	flushTimer *time.Timer
+	flushArmed bool
+
+	rmu  sync.Mutex
+	held []string
+
+	pmu    sync.Mutex
+	acking atomic.Bool
+	acks   chan int64
+
+	// handshakeBy is the HandshakeSec deadline in unix nanoseconds, zero
+	// once Registered is called. atomic because Registered can run while
+	// another goroutine is in readLine.
+	handshakeBy atomic.Int64
+
+	closeOnce sync.Once
+	closeErr  error
+	done      chan struct{}
 }
 
 func Wrap(c net.Conn, cfg Config) *Conn {
@@ -32,29 +65,118 @@ func Wrap(c net.Conn, cfg Config) *Conn {
 	if cfg.TimeoutSec <= 0 {
 		cfg.TimeoutSec = 120
 	}
+	if cfg.CoalesceBytes <= 0 || cfg.CoalesceBytes > writeBufSize {
+		cfg.CoalesceBytes = writeBufSize
+	}
+	if cfg.CoalesceMs <= 0 {
+		cfg.CoalesceMs = 10
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
 
-	return &Conn{
+	r := readerPool.Get().(*bufio.Reader)
+	r.Reset(c)
+	w := writerPool.Get().(*bufio.Writer)
+	w.Reset(c)
+
+	conn := &Conn{
 		NetConn: c,
-		R:       bufio.NewReaderSize(c, cfg.MaxMsgSize),
-		W:       bufio.NewWriter(c),
+		R:       r,
+		W:       w,
 		cfg:     cfg,
+		done:    make(chan struct{}),
+		acks:    make(chan int64, 1),
+	}
+	if cfg.Strict {
+		v := NewValidator(cfg.MaxMsgSize, cfg.Caps)
+		v.Role = cfg.Role
+		conn.v.Store(v)
+	}
+	if cfg.HandshakeSec > 0 {
+		conn.handshakeBy.Store(time.Now().Add(time.Duration(cfg.HandshakeSec) * time.Second).UnixNano())
 	}
+
+	return conn
+}
+
+func (c *Conn) Clock() Clock {
+	return c.cfg.Clock
 }
 
 func (c *Conn) Close() error {
-	return c.NetConn.Close()
+	c.closeOnce.Do(func() {
+		c.closeErr = c.NetConn.Close()
+		close(c.done)
+	})
+	return c.closeErr
+}
+
+// Registered ends the HandshakeSec budget; from here on only the per-read
+// TimeoutSec applies.
+func (c *Conn) Registered() {
+	c.handshakeBy.Store(0)
+}
+
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
 }
 
+// ReadLine returns the next line, starting with any a payload transfer
+// set aside.
 func (c *Conn) ReadLine() (string, error) {
-	_ = c.NetConn.SetReadDeadline(time.Now().Add(time.Duration(c.cfg.TimeoutSec) * time.Second))
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
 
-	line, err := c.R.ReadString('\n')
-	if err != nil {
-		return "", err
+	if len(c.held) > 0 {
+		line := c.held[0]
+		c.held = c.held[1:]
+		return line, nil
+	}
+	return c.nextLine()
+}
+
+func (c *Conn) readLine() (string, error) {
+	if c.R == nil {
+		return "", errors.New("connection released")
 	}
 
-	if len(line) > c.cfg.MaxMsgSize {
-		return "", errors.New("message too big")
+	deadline := time.Now().Add(time.Duration(c.cfg.TimeoutSec) * time.Second)
+	handshakeBy := c.handshakeBy.Load()
+	if handshakeBy != 0 && handshakeBy < deadline.UnixNano() {
+		deadline = time.Unix(0, handshakeBy)
+	}
+	_ = c.NetConn.SetReadDeadline(deadline)
+
+	var line string
+	var buf []byte
+	for {
+		chunk, err := c.R.ReadSlice('\n')
+		if len(buf)+len(chunk) > c.cfg.MaxMsgSize {
+			// skip the rest of the line, otherwise its tail would come back
+			// as the next line
+			for err == bufio.ErrBufferFull {
+				_, err = c.R.ReadSlice('\n')
+			}
+			return "", errors.New("message too big")
+		}
+		if err == bufio.ErrBufferFull {
+			buf = append(buf, chunk...)
+			continue
+		}
+		if err != nil {
+			if by := c.handshakeBy.Load(); by != 0 && time.Now().UnixNano() >= by {
+				return "", errors.New("handshake timed out")
+			}
+			return "", err
+		}
+
+		if buf == nil {
+			line = string(chunk)
+		} else {
+			line = string(append(buf, chunk...))
+		}
+		break
 	}
 
 	line = strings.TrimRight(line, "\r\n")
@@ -63,12 +185,100 @@ func (c *Conn) ReadLine() (string, error) {
 		return "", errors.New("invalid control chars")
 	}
 
+	if err := c.validate(line, "inbound"); err != nil {
+		return "", err
+	}
+
 	return line, nil
 }
 
 func (c *Conn) WriteLine(s string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err := c.queue(s); err != nil {
+		return err
+	}
+
+	return c.W.Flush()
+}
+
+// QueueLine buffers s and only writes once CoalesceBytes have piled up or
+// the oldest queued line is CoalesceMs old, so a burst of lines costs one
+// syscall instead of one each. a timer covers the last line of a burst, so
+// nothing sits in the buffer; call Flush to send right away.
+func (c *Conn) QueueLine(s string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err := c.queue(s); err != nil {
+		return err
+	}
+
+	return c.maybeFlush()
+}
+
+func (c *Conn) maybeFlush() error {
+	window := time.Duration(c.cfg.CoalesceMs) * time.Millisecond
+	age := time.Since(c.queuedAt)
+	if c.W.Buffered() >= c.cfg.CoalesceBytes || age >= window {
+		return c.W.Flush()
+	}
+
+	if !c.flushArmed {
+		c.flushArmed = true
+		if c.flushTimer == nil {
+			c.flushTimer = time.AfterFunc(window-age, c.flushLater)
+		} else {
+			c.flushTimer.Reset(window - age)
+		}
+	}
+	return nil
+}
+
+// flushLater is the timer behind QueueLine's time limit, for lines nothing
+// else gets queued behind.
+func (c *Conn) flushLater() {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	c.flushArmed = false
+	if c.W != nil && c.W.Buffered() > 0 {
+		_ = c.W.Flush()
+	}
+}
+
+func (c *Conn) Flush() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.W == nil || c.W.Buffered() == 0 {
+		return nil
+	}
+
+	_ = c.NetConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.W.Flush()
+}
+
+func (c *Conn) queue(s string) error {
+	if c.W == nil {
+		return errors.New("connection released")
+	}
+	if err := c.checkLine(s); err != nil {
+		return err
+	}
+	if c.W.Buffered() == 0 {
+		c.queuedAt = time.Now()
+	}
+
 	_ = c.NetConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 
+	_, err := c.W.WriteString(s + "\n")
+	return err
+}
+
+// checkLine runs the outbound checks WriteLine would, without writing.
+func (c *Conn) checkLine(s string) error {
 	if len(s) > c.cfg.MaxMsgSize {
 		return errors.New("message too big")
 	}
@@ -77,11 +287,7 @@ func (c *Conn) WriteLine(s string) error {
 		return errors.New("invalid control chars")
 	}
 
-	if _, err := c.W.WriteString(s + "\n"); err != nil {
-		return err
-	}
-
-	return c.W.Flush()
+	return c.validate(s, "outbound")
 }
 
 func ParseCommand(line string) (cmd string, arg string) {
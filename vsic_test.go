@@ -0,0 +1,159 @@
+package vsic
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLineOversizeSkipsTail(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{})
+	defer c.Close()
+
+	go func() {
+		a.Write([]byte("MSG #x " + strings.Repeat("y", 5500) + " BYE injected\nPING\n"))
+	}()
+
+	if _, err := c.ReadLine(); err == nil {
+		t.Fatal("oversized line accepted")
+	}
+
+	line, err := c.ReadLine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "PING" {
+		t.Fatalf("got %.40q after oversized line, want PING", line)
+	}
+}
+
+func TestQueueLineFlushesAlone(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{CoalesceMs: 20})
+	defer c.Close()
+
+	go c.QueueLine("PING")
+
+	peer := Wrap(a, Config{TimeoutSec: 1})
+	line, err := peer.ReadLine()
+	if err != nil {
+		t.Fatalf("queued line never sent: %v", err)
+	}
+	if line != "PING" {
+		t.Fatalf("got %q, want PING", line)
+	}
+}
+
+func TestEnableCap(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{Strict: true})
+	defer c.Close()
+
+	go a.Write([]byte("PAYLOAD 0\nPAYLOAD 0\n"))
+
+	if _, err := c.ReadLine(); err == nil {
+		t.Fatal("PAYLOAD accepted before the cap was enabled")
+	}
+
+	c.EnableCap("payload")
+	if _, err := c.ReadLine(); err != nil {
+		t.Fatalf("PAYLOAD rejected after EnableCap: %v", err)
+	}
+}
+
+func TestStrictServerOnly(t *testing.T) {
+	tests := []struct {
+		role    Role
+		dir     string
+		line    string
+		wantErr bool
+	}{
+		{RoleServer, "inbound", "ERROR 400 bad", true},
+		{RoleServer, "inbound", "NOTICE hi", true},
+		{RoleServer, "outbound", "NOTICE hi", false},
+		{RoleClient, "outbound", "LIMITS rate=1", true},
+		{RoleClient, "inbound", "LIMITS rate=1", false},
+		{RoleClient, "outbound", "MSG #a hi", false},
+		{RoleServer, "inbound", "MSG #a hi", false},
+		{RoleAny, "inbound", "WARN x", false},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(0, nil)
+		v.Role = tt.role
+		if reason := v.check(tt.line, tt.dir); (reason != "") != tt.wantErr {
+			t.Errorf("role %d %s %q: reason %q, want error %v", tt.role, tt.dir, tt.line, reason, tt.wantErr)
+		}
+	}
+}
+
+func TestStrictServerRejectsClientError(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{Strict: true, Role: RoleServer})
+	defer c.Close()
+
+	go a.Write([]byte("ERROR 500 spoofed\n"))
+	go io.Copy(io.Discard, a)
+
+	var v *Violation
+	if _, err := c.ReadLine(); !errors.As(err, &v) || v.Dir != "inbound" {
+		t.Fatalf("ReadLine = %v, want an inbound violation", err)
+	}
+	if err := c.WriteLine("ERROR 400 bad"); err != nil {
+		t.Fatalf("server sending ERROR: %v", err)
+	}
+}
+
+// a peer that keeps sending a byte at a time never hits TimeoutSec, so
+// only the handshake budget gets rid of it.
+func TestHandshakeTrickle(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{HandshakeSec: 1})
+	defer c.Close()
+
+	go func() {
+		for {
+			if _, err := a.Write([]byte("H")); err != nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	_, err := c.ReadLine()
+	if err == nil || err.Error() != "handshake timed out" {
+		t.Fatalf("ReadLine = %v, want handshake timed out", err)
+	}
+	if d := time.Since(start); d > 1500*time.Millisecond {
+		t.Fatalf("timed out after %v with HandshakeSec 1", d)
+	}
+}
+
+func TestRegisteredWhileReading(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	c := Wrap(b, Config{HandshakeSec: 1})
+	defer c.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.ReadLine()
+		errc <- err
+	}()
+
+	c.Registered()
+	a.Write([]byte("PING\n"))
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}